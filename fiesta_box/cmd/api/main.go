@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"time"
 
+	"fiesta_box/internal/config"
 	"fiesta_box/internal/server"
 )
 
@@ -38,7 +39,15 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 
 func main() {
 
-	server := server.NewServer()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("could not load config: %v", err)
+	}
+
+	server, err := server.NewServer(cfg)
+	if err != nil {
+		log.Fatalf("could not create server: %v", err)
+	}
 
 	// Create a done channel to signal when the shutdown is complete
 	done := make(chan bool, 1)
@@ -46,7 +55,7 @@ func main() {
 	// Run graceful shutdown in a separate goroutine
 	go gracefulShutdown(server, done)
 
-	err := server.ListenAndServe()
+	err = server.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
 		panic(fmt.Sprintf("http server error: %s", err))
 	}
@@ -0,0 +1,161 @@
+// Package config gathers every setting the server runs with in one place.
+//
+// Settings start from the defaults of the packages they configure, are then
+// overridden by the optional JSON file named in CONFIG_FILE, and finally by
+// environment variables, which may also come from a .env file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"fiesta_box/internal/database"
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/services"
+	"fiesta_box/internal/sockets"
+)
+
+// ServerConfig holds the HTTP server settings.
+type ServerConfig struct {
+	Port         int
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// MessageTimeout is how long a websocket message may wait on the game
+	// service before it is given up on.
+	MessageTimeout time.Duration
+}
+
+// CORSConfig holds the origins browsers may call the server from.
+type CORSConfig struct {
+	AllowedOrigins []string
+	// DevMode allows every origin. It is only meant for local development.
+	DevMode bool
+}
+
+// Config is everything the server needs to start.
+type Config struct {
+	Server   ServerConfig
+	CORS     CORSConfig
+	Database database.Config
+	Game     services.GameConfig
+	Reaper   services.ReaperConfig
+	Socket   sockets.Config
+}
+
+// Default is the configuration used for anything that isn't set.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:           8080,
+			ReadTimeout:    10 * time.Second,
+			WriteTimeout:   30 * time.Second,
+			IdleTimeout:    time.Minute,
+			MessageTimeout: 5 * time.Second,
+		},
+		Database: database.Config{
+			Driver:         database.DriverPostgres,
+			Path:           "fiesta_box.db",
+			Host:           "localhost",
+			Port:           "5432",
+			Schema:         "public",
+			AutoMigrate:    true,
+			HealthInterval: 15 * time.Second,
+		},
+		Game:   services.DefaultGameConfig,
+		Reaper: services.DefaultReaperConfig,
+		Socket: sockets.DefaultConfig,
+	}
+}
+
+// Load reads the configuration from .env, CONFIG_FILE and the environment,
+// then validates it. Every problem found is reported together.
+func Load() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not read .env: %w", err)
+	}
+
+	config := Default()
+	var errs []error
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		errs = append(errs, config.loadFile(path)...)
+	}
+	errs = append(errs, config.loadEnv(os.LookupEnv)...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate reports every setting that is out of range.
+func (c *Config) Validate() error {
+	var errs []error
+	problem := func(key string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s %s", describe(key), fmt.Sprintf(format, args...)))
+	}
+	positive := func(key string, d time.Duration) {
+		if d <= 0 {
+			problem(key, "must be a positive duration, got %s", d)
+		}
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		problem("server.port", "must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	positive("server.readTimeout", c.Server.ReadTimeout)
+	positive("server.writeTimeout", c.Server.WriteTimeout)
+	positive("server.idleTimeout", c.Server.IdleTimeout)
+	positive("server.messageTimeout", c.Server.MessageTimeout)
+
+	if !slices.Contains(database.Drivers, c.Database.Driver) {
+		problem("database.driver", "must be one of %s, got %q", strings.Join(database.Drivers, ", "), c.Database.Driver)
+	}
+	if c.Database.Driver == database.DriverSQLite && c.Database.Path == "" {
+		problem("database.path", "is required for the %s driver", database.DriverSQLite)
+	}
+	if c.Database.Driver == database.DriverPostgres && c.Database.Port != "" {
+		if port, err := strconv.Atoi(c.Database.Port); err != nil || port < 1 || port > 65535 {
+			problem("database.port", "must be a port number, got %q", c.Database.Port)
+		}
+	}
+	positive("database.healthInterval", c.Database.HealthInterval)
+
+	if c.Game.MaxPlayers < games.MinPlayers || c.Game.MaxPlayers > games.MaxPlayers {
+		problem("game.maxPlayers", "must be between %d and %d, got %d", games.MinPlayers, games.MaxPlayers, c.Game.MaxPlayers)
+	}
+	positive("game.resumeGrace", c.Game.ResumeGrace)
+
+	positive("reaper.interval", c.Reaper.Interval)
+	positive("reaper.emptyTTL", c.Reaper.EmptyTTL)
+	positive("reaper.idleTTL", c.Reaper.IdleTTL)
+
+	if c.Socket.SendBuffer < 1 {
+		problem("socket.sendBuffer", "must be at least 1, got %d", c.Socket.SendBuffer)
+	}
+	if c.Socket.MaxMessageSize < 1 {
+		problem("socket.maxMessageSize", "must be at least 1 byte, got %d", c.Socket.MaxMessageSize)
+	}
+	positive("socket.writeWait", c.Socket.WriteWait)
+	positive("socket.pongWait", c.Socket.PongWait)
+	positive("socket.pingPeriod", c.Socket.PingPeriod)
+	if c.Socket.PingPeriod >= c.Socket.PongWait {
+		problem("socket.pingPeriod", "must be shorter than %s (%s), got %s", describe("socket.pongWait"), c.Socket.PongWait, c.Socket.PingPeriod)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
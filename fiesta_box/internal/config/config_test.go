@@ -0,0 +1,88 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("default config is invalid: %v", err)
+	}
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	config := Default()
+	file := `{
+		"server": {"port": 9000},
+		"game": {"maxPlayers": 6, "lateJoin": true},
+		"reaper": {"idleTTL": "30m"},
+		"cors": {"allowedOrigins": ["https://a.example.com", "https://b.example.com"]}
+	}`
+	if errs := config.readFile(strings.NewReader(file)); len(errs) > 0 {
+		t.Fatalf("unexpected file errors: %v", errs)
+	}
+
+	env := map[string]string{"GAME_MAX_PLAYERS": "4", "SOCKET_PING_PERIOD": "20s", "GAME_EMPTY_TTL": " "}
+	errs := config.loadEnv(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected env errors: %v", errs)
+	}
+
+	if config.Server.Port != 9000 || !config.Game.LateJoin || config.Reaper.IdleTTL != 30*time.Minute {
+		t.Errorf("file settings were not applied: %+v", config)
+	}
+	if config.Game.MaxPlayers != 4 || config.Socket.PingPeriod != 20*time.Second {
+		t.Errorf("env did not override the file: %+v", config)
+	}
+	if config.Reaper.EmptyTTL != Default().Reaper.EmptyTTL {
+		t.Errorf("blank env should keep the default, got %s", config.Reaper.EmptyTTL)
+	}
+	if want := []string{"https://a.example.com", "https://b.example.com"}; !reflect.DeepEqual(config.CORS.AllowedOrigins, want) {
+		t.Errorf("got origins %v, want %v", config.CORS.AllowedOrigins, want)
+	}
+}
+
+func TestBadValuesAreAllReported(t *testing.T) {
+	config := Default()
+	errs := config.readFile(strings.NewReader(`{"game": {"maxPlayer": 6, "lateJoin": "sometimes"}, "server": {"port": {}}}`))
+	errs = append(errs, config.loadEnv(func(key string) (string, bool) {
+		if key == "GAME_IDLE_TTL" {
+			return "forever", true
+		}
+		return "", false
+	})...)
+
+	want := []string{"game.lateJoin", "game.maxPlayer is not a known setting", "server.port", "GAME_IDLE_TTL"}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors %v, want %d", len(errs), errs, len(want))
+	}
+	for i, err := range errs {
+		if !strings.Contains(err.Error(), want[i]) {
+			t.Errorf("error %d = %q, want it to mention %q", i, err, want[i])
+		}
+	}
+}
+
+func TestValidateNamesEverySetting(t *testing.T) {
+	config := Default()
+	config.Server.Port = 0
+	config.Game.MaxPlayers = 1
+	config.Socket.PingPeriod = config.Socket.PongWait
+	config.Database.Driver = "mysql"
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("expected an invalid config")
+	}
+	for _, name := range []string{"PORT", "GAME_MAX_PLAYERS", "SOCKET_PING_PERIOD", "BLUEPRINT_DB_DRIVER"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not mention %s", err, name)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting ties a config field to its key in the config file and its
+// environment variable.
+type setting struct {
+	// key is "section.name" in the config file.
+	key string
+	env string
+	// field points at the value in a Config.
+	field func(c *Config) interface{}
+}
+
+var settings = []setting{
+	{"server.port", "PORT", func(c *Config) interface{} { return &c.Server.Port }},
+	{"server.readTimeout", "SERVER_READ_TIMEOUT", func(c *Config) interface{} { return &c.Server.ReadTimeout }},
+	{"server.writeTimeout", "SERVER_WRITE_TIMEOUT", func(c *Config) interface{} { return &c.Server.WriteTimeout }},
+	{"server.idleTimeout", "SERVER_IDLE_TIMEOUT", func(c *Config) interface{} { return &c.Server.IdleTimeout }},
+	{"server.messageTimeout", "SERVER_MESSAGE_TIMEOUT", func(c *Config) interface{} { return &c.Server.MessageTimeout }},
+
+	{"cors.allowedOrigins", "CORS_ALLOWED_ORIGINS", func(c *Config) interface{} { return &c.CORS.AllowedOrigins }},
+	{"cors.devMode", "CORS_DEV_MODE", func(c *Config) interface{} { return &c.CORS.DevMode }},
+
+	{"database.driver", "BLUEPRINT_DB_DRIVER", func(c *Config) interface{} { return &c.Database.Driver }},
+	{"database.path", "BLUEPRINT_DB_PATH", func(c *Config) interface{} { return &c.Database.Path }},
+	{"database.host", "BLUEPRINT_DB_HOST", func(c *Config) interface{} { return &c.Database.Host }},
+	{"database.port", "BLUEPRINT_DB_PORT", func(c *Config) interface{} { return &c.Database.Port }},
+	{"database.database", "BLUEPRINT_DB_DATABASE", func(c *Config) interface{} { return &c.Database.Database }},
+	{"database.username", "BLUEPRINT_DB_USERNAME", func(c *Config) interface{} { return &c.Database.Username }},
+	{"database.password", "BLUEPRINT_DB_PASSWORD", func(c *Config) interface{} { return &c.Database.Password }},
+	{"database.schema", "BLUEPRINT_DB_SCHEMA", func(c *Config) interface{} { return &c.Database.Schema }},
+	{"database.autoMigrate", "BLUEPRINT_DB_AUTO_MIGRATE", func(c *Config) interface{} { return &c.Database.AutoMigrate }},
+	{"database.healthInterval", "BLUEPRINT_DB_HEALTH_INTERVAL", func(c *Config) interface{} { return &c.Database.HealthInterval }},
+
+	{"game.maxPlayers", "GAME_MAX_PLAYERS", func(c *Config) interface{} { return &c.Game.MaxPlayers }},
+	{"game.lateJoin", "GAME_LATE_JOIN", func(c *Config) interface{} { return &c.Game.LateJoin }},
+	{"game.resumeGrace", "GAME_RESUME_GRACE", func(c *Config) interface{} { return &c.Game.ResumeGrace }},
+
+	{"reaper.interval", "GAME_REAP_INTERVAL", func(c *Config) interface{} { return &c.Reaper.Interval }},
+	{"reaper.emptyTTL", "GAME_EMPTY_TTL", func(c *Config) interface{} { return &c.Reaper.EmptyTTL }},
+	{"reaper.idleTTL", "GAME_IDLE_TTL", func(c *Config) interface{} { return &c.Reaper.IdleTTL }},
+
+	{"socket.sendBuffer", "SOCKET_SEND_BUFFER", func(c *Config) interface{} { return &c.Socket.SendBuffer }},
+	{"socket.writeWait", "SOCKET_WRITE_WAIT", func(c *Config) interface{} { return &c.Socket.WriteWait }},
+	{"socket.pongWait", "SOCKET_PONG_WAIT", func(c *Config) interface{} { return &c.Socket.PongWait }},
+	{"socket.pingPeriod", "SOCKET_PING_PERIOD", func(c *Config) interface{} { return &c.Socket.PingPeriod }},
+	{"socket.maxMessageSize", "SOCKET_MAX_MESSAGE_SIZE", func(c *Config) interface{} { return &c.Socket.MaxMessageSize }},
+}
+
+// describe names a setting by both its file key and environment variable.
+func describe(key string) string {
+	for _, s := range settings {
+		if s.key == key {
+			return fmt.Sprintf("%s (%s)", s.key, s.env)
+		}
+	}
+	return key
+}
+
+// loadEnv applies every environment variable that is set and not blank.
+func (c *Config) loadEnv(lookup func(string) (string, bool)) []error {
+	var errs []error
+	for _, s := range settings {
+		value, ok := lookup(s.env)
+		if !ok || strings.TrimSpace(value) == "" {
+			continue
+		}
+		if err := parseInto(s.field(c), value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %w", s.env, value, err))
+		}
+	}
+	return errs
+}
+
+func (c *Config) loadFile(path string) []error {
+	file, err := os.Open(path)
+	if err != nil {
+		return []error{fmt.Errorf("could not read config file: %w", err)}
+	}
+	defer file.Close()
+
+	errs := c.readFile(file)
+	for i, err := range errs {
+		errs[i] = fmt.Errorf("%s: %w", path, err)
+	}
+	return errs
+}
+
+// readFile applies a JSON config file made of sections of settings, e.g.
+// {"game": {"maxPlayers": 8}, "reaper": {"idleTTL": "30m"}}. Keys that don't
+// name a setting are reported so that typos don't go unnoticed.
+func (c *Config) readFile(r io.Reader) []error {
+	var sections map[string]map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&sections); err != nil {
+		return []error{fmt.Errorf("config file is not valid JSON: %w", err)}
+	}
+
+	fields := map[string]interface{}{}
+	for _, s := range settings {
+		fields[s.key] = s.field(c)
+	}
+
+	var keys []string
+	for section, values := range sections {
+		for name := range values {
+			keys = append(keys, section+"."+name)
+		}
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		section, name, _ := strings.Cut(key, ".")
+		field, ok := fields[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s is not a known setting", key))
+			continue
+		}
+		value, err := fileValue(sections[section][name])
+		if err == nil {
+			err = parseInto(field, value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", key, err))
+		}
+	}
+	return errs
+}
+
+// fileValue turns a JSON value into the same text an environment variable
+// would hold, so both sources share one parser.
+func fileValue(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case len(raw) > 0 && raw[0] == '"':
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	case len(raw) > 0 && raw[0] == '[':
+		var list []string
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return "", fmt.Errorf("must be a list of strings")
+		}
+		return strings.Join(list, ","), nil
+	case len(raw) > 0 && raw[0] == '{':
+		return "", fmt.Errorf("must not be an object")
+	default:
+		return string(raw), nil
+	}
+}
+
+func parseInto(field interface{}, value string) error {
+	value = strings.TrimSpace(value)
+	switch target := field.(type) {
+	case *string:
+		*target = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be a whole number")
+		}
+		*target = n
+	case *int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("must be a whole number")
+		}
+		*target = n
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+		*target = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 30s or 5m")
+		}
+		*target = d
+	case *[]string:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*target = list
+	default:
+		return fmt.Errorf("unsupported setting type %T", field)
+	}
+	return nil
+}
@@ -3,59 +3,152 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"net/url"
 	"strconv"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
-	_ "github.com/joho/godotenv/autoload"
+	_ "modernc.org/sqlite"
 )
 
+var ErrUnavailable = errors.New("the database is unavailable")
+
+// The drivers the server can keep its data with.
+const (
+	DriverPostgres = "postgres"
+	// DriverSQLite keeps everything in a single file, for local development
+	// and single-box deployments.
+	DriverSQLite = "sqlite"
+	// DriverMemory keeps everything in memory until the server stops.
+	DriverMemory = "memory"
+)
+
+// Drivers lists every supported driver.
+var Drivers = []string{DriverPostgres, DriverSQLite, DriverMemory}
+
 // Service represents a service that interacts with a database.
 type Service interface {
 	// Health returns a map of health status information.
 	// The keys and values in the map are service-specific.
+	// A database that can't be reached reports status "down" rather than
+	// failing.
 	Health() map[string]string
 
 	// Close terminates the database connection.
 	// It returns an error if the connection cannot be closed.
 	Close() error
+
+	// Prompts returns the saved prompt library.
+	Prompts() PromptRepository
+
+	// Users returns the user store.
+	Users() UserRepository
+
+	// Migrator returns the migrator for the schema this build expects.
+	Migrator() (*Migrator, error)
 }
 
+// service is a Service backed by a SQL database.
 type service struct {
-	db *sql.DB
+	db      *sql.DB
+	config  Config
+	dialect *dialect
+	prompts PromptRepository
+	users   UserRepository
 }
 
-var (
-	database   = os.Getenv("BLUEPRINT_DB_DATABASE")
-	password   = os.Getenv("BLUEPRINT_DB_PASSWORD")
-	username   = os.Getenv("BLUEPRINT_DB_USERNAME")
-	port       = os.Getenv("BLUEPRINT_DB_PORT")
-	host       = os.Getenv("BLUEPRINT_DB_HOST")
-	schema     = os.Getenv("BLUEPRINT_DB_SCHEMA")
-	dbInstance *service
-)
+// Config says which database to use and how to reach it.
+type Config struct {
+	// Driver is one of Drivers.
+	Driver string
+	// Path is the SQLite database file, or ":memory:" for a throwaway one.
+	Path string
+	// AutoMigrate applies pending migrations when the server starts, or once
+	// the database comes back if it was down then.
+	AutoMigrate bool
+	// HealthInterval is how often the server checks the database is reachable.
+	HealthInterval time.Duration
+
+	// Host, Port, Database, Username, Password and Schema only apply to Postgres.
+	Host     string
+	Port     string
+	Database string
+	Username string
+	Password string
+	Schema   string
+}
+
+// ConnString is the Postgres URL for the config.
+func (c Config) ConnString() string {
+	// fail fast rather than hang health checks while Postgres is away
+	query := url.Values{"sslmode": {"disable"}, "connect_timeout": {"5"}}
+	if c.Schema != "" {
+		query.Set("search_path", c.Schema)
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.Username, c.Password),
+		Host:     fmt.Sprintf("%s:%s", c.Host, c.Port),
+		Path:     "/" + c.Database,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
 
-func New() Service {
+// SQLiteDSN is the modernc.org/sqlite data source for the config.
+func (c Config) SQLiteDSN() string {
+	query := url.Values{"_pragma": {"busy_timeout(5000)", "foreign_keys(1)", "journal_mode(WAL)"}}
+	return fmt.Sprintf("file:%s?%s", c.Path, query.Encode())
+}
+
+var dbInstance Service
+
+func New(config Config) Service {
 	// Reuse Connection
 	if dbInstance != nil {
 		return dbInstance
 	}
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s", username, password, host, port, database, schema)
-	db, err := sql.Open("pgx", connStr)
-	if err != nil {
-		log.Fatal(err)
-	}
-	dbInstance = &service{
-		db: db,
+
+	switch config.Driver {
+	case DriverMemory:
+		dbInstance = newMemoryService()
+	case DriverSQLite:
+		db, err := sql.Open("sqlite", config.SQLiteDSN())
+		if err != nil {
+			log.Fatal(err)
+		}
+		// SQLite allows one writer at a time, and every connection to
+		// ":memory:" would otherwise get its own empty database
+		db.SetMaxOpenConns(1)
+		dbInstance = &service{
+			db:      db,
+			config:  config,
+			dialect: sqliteDialect,
+			prompts: sqliteDialect.prompts(db),
+			users:   sqliteDialect.users(db),
+		}
+	default:
+		db, err := sql.Open("pgx", config.ConnString())
+		if err != nil {
+			log.Fatal(err)
+		}
+		dbInstance = &service{
+			db:      db,
+			config:  config,
+			dialect: postgresDialect,
+			prompts: postgresDialect.prompts(db),
+			users:   postgresDialect.users(db),
+		}
 	}
 	return dbInstance
 }
 
 // Health checks the health of the database connection by pinging the database.
-// It returns a map with keys indicating various health statistics.
+// It returns a map with keys indicating various health statistics, with status
+// "down" and the error when the database can't be reached.
 func (s *service) Health() map[string]string {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
@@ -67,7 +160,8 @@ func (s *service) Health() map[string]string {
 	if err != nil {
 		stats["status"] = "down"
 		stats["error"] = fmt.Sprintf("db down: %v", err)
-		log.Fatalf("db down: %v", err) // Log the error and terminate the program
+		// games don't need the database, so a failed ping only degrades the server
+		log.Printf("db down: %v", err)
 		return stats
 	}
 
@@ -105,11 +199,30 @@ func (s *service) Health() map[string]string {
 	return stats
 }
 
+// Prompts returns the saved prompt library backed by this database.
+func (s *service) Prompts() PromptRepository {
+	return s.prompts
+}
+
+// Users returns the user store backed by this database.
+func (s *service) Users() UserRepository {
+	return s.users
+}
+
+// Migrator returns the migrator for the migrations embedded in this build.
+func (s *service) Migrator() (*Migrator, error) {
+	return newMigrator(s.db, s.dialect)
+}
+
 // Close closes the database connection.
 // It logs a message indicating the disconnection from the specific database.
 // If the connection is successfully closed, it returns nil.
 // If an error occurs while closing the connection, it returns the error.
 func (s *service) Close() error {
-	log.Printf("Disconnected from database: %s", database)
+	name := s.config.Database
+	if s.dialect == sqliteDialect {
+		name = s.config.Path
+	}
+	log.Printf("Disconnected from database: %s", name)
 	return s.db.Close()
 }
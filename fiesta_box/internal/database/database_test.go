@@ -2,7 +2,12 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"log"
+	"net"
+	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -11,7 +16,20 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
-func mustStartPostgresContainer() (func(context.Context, ...testcontainers.TerminateOption) error, error) {
+var testConfig = Config{Driver: DriverPostgres}
+
+// postgresReady is set once the Postgres container is up. Without Docker only
+// the SQLite and memory stores are tested.
+var postgresReady bool
+
+func mustStartPostgresContainer() (teardown func(context.Context, ...testcontainers.TerminateOption) error, err error) {
+	defer func() {
+		// testcontainers panics when it can't find Docker at all
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
 	var (
 		dbName = "database"
 		dbPwd  = "password"
@@ -33,9 +51,9 @@ func mustStartPostgresContainer() (func(context.Context, ...testcontainers.Termi
 		return nil, err
 	}
 
-	database = dbName
-	password = dbPwd
-	username = dbUser
+	testConfig.Database = dbName
+	testConfig.Password = dbPwd
+	testConfig.Username = dbUser
 
 	dbHost, err := dbContainer.Host(context.Background())
 	if err != nil {
@@ -47,8 +65,8 @@ func mustStartPostgresContainer() (func(context.Context, ...testcontainers.Termi
 		return dbContainer.Terminate, err
 	}
 
-	host = dbHost
-	port = dbPort.Port()
+	testConfig.Host = dbHost
+	testConfig.Port = dbPort.Port()
 
 	return dbContainer.Terminate, err
 }
@@ -56,7 +74,9 @@ func mustStartPostgresContainer() (func(context.Context, ...testcontainers.Termi
 func TestMain(m *testing.M) {
 	teardown, err := mustStartPostgresContainer()
 	if err != nil {
-		log.Fatalf("could not start postgres container: %v", err)
+		log.Printf("could not start postgres container, skipping postgres tests: %v", err)
+	} else {
+		postgresReady = true
 	}
 
 	m.Run()
@@ -66,15 +86,49 @@ func TestMain(m *testing.M) {
 	}
 }
 
+// requirePostgres skips the test when there is no Postgres container.
+func requirePostgres(t *testing.T) {
+	t.Helper()
+	if !postgresReady {
+		t.Skip("postgres container is not running")
+	}
+}
+
+// openTestDB opens an empty database for the dialect.
+func openTestDB(t *testing.T, d *dialect) *sql.DB {
+	t.Helper()
+
+	driver, dsn := "sqlite", Config{Path: filepath.Join(t.TempDir(), "test.db")}.SQLiteDSN()
+	if d == postgresDialect {
+		requirePostgres(t)
+		driver, dsn = "pgx", testConfig.ConnString()
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatalf("could not open %s database: %v", d.name, err)
+	}
+	if d == sqliteDialect {
+		// like New, SQLite gets a single connection
+		db.SetMaxOpenConns(1)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+var testDialects = []*dialect{sqliteDialect, postgresDialect}
+
 func TestNew(t *testing.T) {
-	srv := New()
+	requirePostgres(t)
+	srv := New(testConfig)
 	if srv == nil {
 		t.Fatal("New() returned nil")
 	}
 }
 
 func TestHealth(t *testing.T) {
-	srv := New()
+	requirePostgres(t)
+	srv := New(testConfig)
 
 	stats := srv.Health()
 
@@ -91,8 +145,32 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+func TestHealthReportsDown(t *testing.T) {
+	// a port nothing listens on stands in for Postgres going away
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not find a free port: %v", err)
+	}
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+
+	config := Config{Driver: DriverPostgres, Host: "127.0.0.1", Port: port, Database: "database"}
+	db, err := sql.Open("pgx", config.ConnString())
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	srv := &service{db: db, config: config, dialect: postgresDialect}
+	defer srv.Close()
+
+	stats := srv.Health()
+	if stats["status"] != "down" || stats["error"] == "" {
+		t.Fatalf("expected status down with an error, got %v", stats)
+	}
+}
+
 func TestClose(t *testing.T) {
-	srv := New()
+	requirePostgres(t)
+	srv := New(testConfig)
 
 	if srv.Close() != nil {
 		t.Fatalf("expected Close() to return nil")
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// dialect is what differs between the SQL databases the server can keep its
+// data in.
+type dialect struct {
+	name string
+	// migrations is the directory under migrations/ holding this dialect's scripts.
+	migrations      string
+	migrationsTable string
+	// bind rewrites a query written with $1-style placeholders for the driver.
+	bind func(query string) string
+	// lock keeps other processes from migrating until unlock is called. It
+	// must be taken and released on the same connection. unlock is given the
+	// run's error and returns the one to report.
+	lock func(ctx context.Context, conn *sql.Conn) (unlock func(err error) error, err error)
+	// apply runs one migration's statements on the locked connection, so
+	// that a failure leaves none of them behind.
+	apply func(ctx context.Context, conn *sql.Conn, fn func(tx execer) error) error
+	// prompts builds the saved prompt library on the database.
+	prompts func(db *sql.DB) PromptRepository
+	// users builds the user store on the database.
+	users func(db *sql.DB) UserRepository
+}
+
+var postgresDialect = &dialect{
+	name:       DriverPostgres,
+	migrations: "postgres",
+	migrationsTable: `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	checksum   TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`,
+	bind:    func(query string) string { return query },
+	lock:    postgresAdvisoryLock,
+	apply:   inTx,
+	prompts: func(db *sql.DB) PromptRepository { return newPromptRepository(db) },
+	users:   func(db *sql.DB) UserRepository { return newUserRepository(db) },
+}
+
+var sqlitePlaceholder = regexp.MustCompile(`\$\d+`)
+
+var sqliteDialect = &dialect{
+	name:       DriverSQLite,
+	migrations: "sqlite",
+	migrationsTable: `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	checksum   TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`,
+	// queries bind each placeholder once and in order, so ? works the same
+	bind: func(query string) string { return sqlitePlaceholder.ReplaceAllString(query, "?") },
+	lock: sqliteRunLock,
+	// the run is already one transaction, which unlock rolls back on failure
+	apply:   func(ctx context.Context, conn *sql.Conn, fn func(tx execer) error) error { return fn(conn) },
+	prompts: func(db *sql.DB) PromptRepository { return newSQLitePromptRepository(db) },
+	users:   func(db *sql.DB) UserRepository { return newSQLiteUserRepository(db) },
+}
+
+// migrationLockKey identifies the advisory lock held while migrating, so that
+// two servers starting at once don't both apply the same migration.
+const migrationLockKey int64 = 0x6669657374615f6d // "fiesta_m"
+
+func postgresAdvisoryLock(ctx context.Context, conn *sql.Conn) (func(error) error, error) {
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return nil, fmt.Errorf("could not take the migration lock: %w", err)
+	}
+	return func(err error) error {
+		// a cancelled ctx must not leave the lock held on a pooled connection
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+			log.Printf("could not release the migration lock: %v", err)
+			// throw the connection away rather than return it to the pool locked
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		// each migration was committed on its own, so the run's error stands
+		return err
+	}, nil
+}
+
+// sqliteRunLock wraps the whole run in a write transaction, since SQLite has
+// no advisory locks. BEGIN IMMEDIATE takes the database's write lock up
+// front, so a run in another process waits here instead of reading a history
+// that is about to change. It also makes a SQLite run all or nothing.
+func sqliteRunLock(ctx context.Context, conn *sql.Conn) (func(error) error, error) {
+	for {
+		_, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`)
+		if err == nil {
+			break
+		}
+		// the busy timeout ran out while another run held the lock
+		var sqliteErr *sqlite.Error
+		if !errors.As(err, &sqliteErr) || sqliteErr.Code()&0xff != sqlite3.SQLITE_BUSY || ctx.Err() != nil {
+			return nil, fmt.Errorf("could not take the migration lock: %w", err)
+		}
+	}
+	return func(err error) error {
+		// a cancelled ctx must not leave the transaction open on a pooled connection
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err == nil {
+			if _, err = conn.ExecContext(unlockCtx, `COMMIT`); err == nil {
+				return nil
+			}
+			err = fmt.Errorf("could not commit the migrations: %w", err)
+		}
+		if _, rollbackErr := conn.ExecContext(unlockCtx, `ROLLBACK`); rollbackErr != nil {
+			log.Printf("could not release the migration lock: %v", rollbackErr)
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		return err
+	}, nil
+}
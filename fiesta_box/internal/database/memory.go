@@ -0,0 +1,292 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/models/users"
+)
+
+var ErrNoSchema = errors.New("the memory store has no schema to migrate")
+
+// memoryService is a Service that keeps everything in memory. Nothing survives
+// a restart, which makes it handy for tests and trying the server out.
+type memoryService struct {
+	prompts *memoryPromptRepository
+	users   *memoryUserRepository
+}
+
+func newMemoryService() *memoryService {
+	users := newMemoryUserRepository()
+	return &memoryService{prompts: newMemoryPromptRepository(users), users: users}
+}
+
+func (s *memoryService) Health() map[string]string {
+	s.prompts.mutex.RLock()
+	savedPrompts := len(s.prompts.saved)
+	s.prompts.mutex.RUnlock()
+	s.users.mutex.RLock()
+	userCount := len(s.users.users)
+	s.users.mutex.RUnlock()
+
+	return map[string]string{
+		"status":        "up",
+		"message":       "It's healthy",
+		"driver":        DriverMemory,
+		"saved_prompts": strconv.Itoa(savedPrompts),
+		"users":         strconv.Itoa(userCount),
+	}
+}
+
+func (s *memoryService) Close() error {
+	return nil
+}
+
+func (s *memoryService) Prompts() PromptRepository {
+	return s.prompts
+}
+
+func (s *memoryService) Users() UserRepository {
+	return s.users
+}
+
+func (s *memoryService) Migrator() (*Migrator, error) {
+	return nil, ErrNoSchema
+}
+
+type memoryPromptRepository struct {
+	saved map[string]prompts.SavedPrompt
+	// users is checked for authors, as the SQL stores' foreign key would
+	users *memoryUserRepository
+	mutex sync.RWMutex
+}
+
+func newMemoryPromptRepository(users *memoryUserRepository) *memoryPromptRepository {
+	return &memoryPromptRepository{saved: map[string]prompts.SavedPrompt{}, users: users}
+}
+
+func (r *memoryPromptRepository) Create(ctx context.Context, prompt *prompts.SavedPrompt) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if prompt.AuthorID != "" {
+		if _, err := r.users.Get(ctx, prompt.AuthorID); err != nil {
+			return err
+		}
+	}
+
+	prompt.ID = uuid.NewString()
+	prompt.Tags = prompts.NormalizeTags(prompt.Tags)
+	prompt.CreatedAt = time.Now().UTC()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.saved[prompt.ID] = copyPrompt(*prompt)
+	return nil
+}
+
+func (r *memoryPromptRepository) Get(ctx context.Context, id string) (*prompts.SavedPrompt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	prompt, ok := r.saved[id]
+	if !ok {
+		return nil, ErrPromptNotFound
+	}
+	copied := copyPrompt(prompt)
+	return &copied, nil
+}
+
+func (r *memoryPromptRepository) List(ctx context.Context, limit int, offset int) ([]prompts.SavedPrompt, error) {
+	return r.Search(ctx, prompts.SavedPromptQuery{Limit: limit, Offset: offset})
+}
+
+func (r *memoryPromptRepository) Search(ctx context.Context, query prompts.SavedPromptQuery) ([]prompts.SavedPrompt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	text := strings.ToLower(strings.TrimSpace(query.Text))
+	category := strings.TrimSpace(query.Category)
+	tag := strings.ToLower(strings.TrimSpace(query.Tag))
+	author := strings.TrimSpace(query.Author)
+	authorID := strings.TrimSpace(query.AuthorID)
+
+	r.mutex.RLock()
+	found := []prompts.SavedPrompt{}
+	for _, prompt := range r.saved {
+		switch {
+		case text != "" && !strings.Contains(strings.ToLower(prompt.Text), text):
+		case category != "" && prompt.Category != category:
+		case tag != "" && !hasTag(prompt, tag):
+		case author != "" && prompt.Author != author:
+		case authorID != "" && prompt.AuthorID != authorID:
+		default:
+			found = append(found, copyPrompt(prompt))
+		}
+	}
+	r.mutex.RUnlock()
+
+	// newest first, like the SQL stores
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].CreatedAt.Equal(found[j].CreatedAt) {
+			return found[i].CreatedAt.After(found[j].CreatedAt)
+		}
+		return found[i].ID < found[j].ID
+	})
+
+	limit, offset := query.Page()
+	if offset >= len(found) {
+		return []prompts.SavedPrompt{}, nil
+	}
+	found = found[offset:]
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
+
+func (r *memoryPromptRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.saved[id]; !ok {
+		return ErrPromptNotFound
+	}
+	delete(r.saved, id)
+	return nil
+}
+
+func hasTag(prompt prompts.SavedPrompt, tag string) bool {
+	for _, t := range prompt.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// copyPrompt keeps callers from changing a stored prompt's tags in place.
+func copyPrompt(prompt prompts.SavedPrompt) prompts.SavedPrompt {
+	prompt.Tags = append([]string{}, prompt.Tags...)
+	return prompt
+}
+
+type memoryUserRepository struct {
+	users map[string]users.User
+	// games holds each user's history in the order it was recorded
+	games map[string][]users.GameRecord
+	mutex sync.RWMutex
+}
+
+func newMemoryUserRepository() *memoryUserRepository {
+	return &memoryUserRepository{users: map[string]users.User{}, games: map[string][]users.GameRecord{}}
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *users.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	user.ID = uuid.NewString()
+	user.CreatedAt = time.Now().UTC()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) Get(ctx context.Context, id string) (*users.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) RecordGame(ctx context.Context, userID string, record users.GameRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.users[userID]; !ok {
+		return ErrUserNotFound
+	}
+	for _, recorded := range r.games[userID] {
+		if recorded.GameID == record.GameID {
+			return nil
+		}
+	}
+	r.games[userID] = append(r.games[userID], record)
+	return nil
+}
+
+func (r *memoryUserRepository) History(ctx context.Context, userID string, limit int, offset int) ([]users.GameRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	history := append([]users.GameRecord{}, r.games[userID]...)
+	r.mutex.RUnlock()
+
+	// newest first, like the SQL stores
+	sort.Slice(history, func(i, j int) bool {
+		if !history[i].CompletedAt.Equal(history[j].CompletedAt) {
+			return history[i].CompletedAt.After(history[j].CompletedAt)
+		}
+		return history[i].GameID < history[j].GameID
+	})
+
+	limit, offset = users.HistoryPage(limit, offset)
+	if offset >= len(history) {
+		return []users.GameRecord{}, nil
+	}
+	history = history[offset:]
+	if len(history) > limit {
+		history = history[:limit]
+	}
+	return history, nil
+}
+
+func (r *memoryUserRepository) Totals(ctx context.Context, userID string) (users.Totals, error) {
+	var totals users.Totals
+	if err := ctx.Err(); err != nil {
+		return totals, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, record := range r.games[userID] {
+		totals.Games++
+		totals.Performed += record.Performed
+		totals.Drank += record.Drank
+		if record.MVP {
+			totals.MVPs++
+		}
+	}
+	return totals, nil
+}
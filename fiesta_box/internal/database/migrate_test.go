@@ -0,0 +1,241 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(fstest.MapFS{
+		"0002_add_users.up.sql":     {Data: []byte("CREATE TABLE users (id INT);")},
+		"0002_add_users.down.sql":   {Data: []byte("DROP TABLE users;")},
+		"0001_add_prompts.up.sql":   {Data: []byte("CREATE TABLE prompts (id INT);")},
+		"0001_add_prompts.down.sql": {Data: []byte("DROP TABLE prompts;")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "add_prompts" || migrations[1].Name != "add_users" {
+		t.Fatalf("expected migrations in version order, got %+v", migrations)
+	}
+	if migrations[0].Down != "DROP TABLE prompts;" || migrations[0].Checksum == "" {
+		t.Fatalf("expected the down script and a checksum, got %+v", migrations[0])
+	}
+}
+
+func TestLoadMigrationsRejectsBadFiles(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"bad name":          {"add_users.sql": {}},
+		"missing up":        {"0001_add_users.down.sql": {Data: []byte("DROP TABLE users;")}},
+		"duplicate version": {"0001_a.up.sql": {Data: []byte("SELECT 1;")}, "0001_b.up.sql": {Data: []byte("SELECT 1;")}},
+		"version zero":      {"0000_a.up.sql": {Data: []byte("SELECT 1;")}},
+	}
+	for name, files := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadMigrations(files); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	versions := map[string][]int{}
+	for _, d := range testDialects {
+		migrator, err := newMigrator(nil, d)
+		if err != nil {
+			t.Fatalf("embedded %s migrations are invalid: %v", d.name, err)
+		}
+		for _, migration := range migrator.migrations {
+			if migration.Down == "" {
+				t.Errorf("%s migration %04d_%s has no down script", d.name, migration.Version, migration.Name)
+			}
+			versions[d.name] = append(versions[d.name], migration.Version)
+		}
+	}
+
+	// every store moves through the same schema versions
+	if fmt.Sprint(versions[DriverSQLite]) != fmt.Sprint(versions[DriverPostgres]) {
+		t.Fatalf("sqlite migrations %v don't match postgres migrations %v", versions[DriverSQLite], versions[DriverPostgres])
+	}
+}
+
+func TestMigratorUpDownAndChecksums(t *testing.T) {
+	for _, d := range testDialects {
+		t.Run(d.name, func(t *testing.T) {
+			testMigratorUpDownAndChecksums(t, d)
+		})
+	}
+}
+
+func testMigratorUpDownAndChecksums(t *testing.T, d *dialect) {
+	ctx := context.Background()
+	db := openTestDB(t, d)
+
+	migrator := &Migrator{db: db, dialect: d, migrations: []Migration{
+		{Version: 1, Name: "widgets", Up: "CREATE TABLE widgets (id INT);", Down: "DROP TABLE widgets;", Checksum: "one"},
+		{Version: 2, Name: "gadgets", Up: "CREATE TABLE gadgets (id INT);", Down: "DROP TABLE gadgets;", Checksum: "two"},
+	}}
+	defer func() {
+		for _, table := range []string{"widgets", "gadgets", "schema_migrations"} {
+			db.ExecContext(ctx, "DROP TABLE IF EXISTS "+table)
+		}
+	}()
+
+	// concurrent runs wait on the lock instead of applying twice
+	var wg sync.WaitGroup
+	applied := make([]int, 3)
+	errs := make([]error, 3)
+	for i := range applied {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			migrations, err := migrator.Up(ctx)
+			applied[i], errs[i] = len(migrations), err
+		}(i)
+	}
+	wg.Wait()
+	total := 0
+	for i := range applied {
+		if errs[i] != nil {
+			t.Fatalf("migrate up failed: %v", errs[i])
+		}
+		total += applied[i]
+	}
+	if total != 2 {
+		t.Fatalf("expected 2 migrations applied across runs, got %d", total)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("could not get status: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Fatalf("expected migration %d to be applied", status.Version)
+		}
+	}
+
+	rolledBack, err := migrator.Down(ctx, 1)
+	if err != nil || len(rolledBack) != 1 || rolledBack[0].Version != 2 {
+		t.Fatalf("expected to roll back migration 2, got %+v, %v", rolledBack, err)
+	}
+
+	migrator.migrations[0].Checksum = "edited"
+	if _, err := migrator.Up(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+
+	migrator.migrations = nil
+	if _, err := migrator.Up(ctx); !errors.Is(err, ErrUnknownMigration) || !strings.Contains(err.Error(), "widgets") {
+		t.Fatalf("expected ErrUnknownMigration for widgets, got %v", err)
+	}
+}
+
+// openSQLiteFile opens the file the way a separate migrate process would,
+// with a connection of its own.
+func openSQLiteFile(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", Config{Path: path}.SQLiteDSN())
+	if err != nil {
+		t.Fatalf("could not open sqlite database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLiteMigratorWaitsForOtherProcesses(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	migrations := []Migration{
+		{Version: 1, Name: "widgets", Up: "CREATE TABLE widgets (id INT);", Down: "DROP TABLE widgets;", Checksum: "one"},
+		{Version: 2, Name: "gadgets", Up: "CREATE TABLE gadgets (id INT);", Down: "DROP TABLE gadgets;", Checksum: "two"},
+	}
+	first := &Migrator{db: openSQLiteFile(t, path), dialect: sqliteDialect, migrations: migrations}
+	second := &Migrator{db: openSQLiteFile(t, path), dialect: sqliteDialect, migrations: migrations}
+
+	// hold the first run's lock while the second starts
+	conn, err := first.db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("could not connect: %v", err)
+	}
+	unlock, err := sqliteDialect.lock(ctx, conn)
+	if err != nil {
+		t.Fatalf("could not take the migration lock: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := second.Up(ctx)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("expected the second run to wait for the lock, it finished with %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if _, err := conn.ExecContext(ctx, migrations[0].Up); err != nil {
+		t.Fatalf("could not apply widgets: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, sqliteDialect.migrationsTable); err != nil {
+		t.Fatalf("could not create the migrations table: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES (1, 'widgets', 'one')`); err != nil {
+		t.Fatalf("could not record widgets: %v", err)
+	}
+	if err := unlock(nil); err != nil {
+		t.Fatalf("could not release the migration lock: %v", err)
+	}
+	conn.Close()
+
+	// the second run saw what the first applied instead of applying it again
+	if err := <-done; err != nil {
+		t.Fatalf("migrate up failed: %v", err)
+	}
+	statuses, err := first.Status(ctx)
+	if err != nil {
+		t.Fatalf("could not get status: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Fatalf("expected migration %d to be applied", status.Version)
+		}
+	}
+}
+
+func TestSQLiteMigratorRunIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, sqliteDialect)
+	migrator := &Migrator{db: db, dialect: sqliteDialect, migrations: []Migration{
+		{Version: 1, Name: "widgets", Up: "CREATE TABLE widgets (id INT);", Down: "DROP TABLE widgets;", Checksum: "one"},
+		{Version: 2, Name: "broken", Up: "CREATE TABLE gadgets (id INT); NOT SQL;", Down: "DROP TABLE gadgets;", Checksum: "two"},
+	}}
+
+	if _, err := migrator.Up(ctx); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("expected migration broken to fail, got %v", err)
+	}
+
+	var tables int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE name IN ('widgets', 'gadgets', 'schema_migrations')`).Scan(&tables); err != nil {
+		t.Fatalf("could not list tables: %v", err)
+	}
+	if tables != 0 {
+		t.Fatalf("expected the failed run to leave no tables, found %d", tables)
+	}
+
+	// the connection went back to the pool without a transaction open
+	migrator.migrations = migrator.migrations[:1]
+	if applied, err := migrator.Up(ctx); err != nil || len(applied) != 1 {
+		t.Fatalf("expected widgets to apply, got %+v, %v", applied, err)
+	}
+}
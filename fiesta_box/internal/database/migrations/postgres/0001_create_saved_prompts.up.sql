@@ -0,0 +1,11 @@
+CREATE TABLE saved_prompts (
+	id         UUID PRIMARY KEY,
+	text       TEXT NOT NULL,
+	category   TEXT NOT NULL DEFAULT '',
+	tags       TEXT[] NOT NULL DEFAULT '{}',
+	author     TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX saved_prompts_created_at_idx ON saved_prompts (created_at DESC);
+CREATE INDEX saved_prompts_tags_idx ON saved_prompts USING GIN (tags);
@@ -0,0 +1,3 @@
+ALTER TABLE saved_prompts DROP COLUMN author_id;
+
+DROP TABLE users;
@@ -0,0 +1,12 @@
+CREATE TABLE users (
+	id           UUID PRIMARY KEY,
+	display_name TEXT NOT NULL,
+	avatar_url   TEXT NOT NULL DEFAULT '',
+	-- SHA-256 of the user's token, never the token itself
+	token_hash   TEXT NOT NULL,
+	created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE saved_prompts ADD COLUMN author_id UUID REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX saved_prompts_author_id_idx ON saved_prompts (author_id);
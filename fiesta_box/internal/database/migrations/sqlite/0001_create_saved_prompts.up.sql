@@ -0,0 +1,12 @@
+CREATE TABLE saved_prompts (
+	id         TEXT PRIMARY KEY,
+	text       TEXT NOT NULL,
+	category   TEXT NOT NULL DEFAULT '',
+	-- a JSON array of strings
+	tags       TEXT NOT NULL DEFAULT '[]',
+	author     TEXT NOT NULL DEFAULT '',
+	-- unix nanoseconds
+	created_at INTEGER NOT NULL
+);
+
+CREATE INDEX saved_prompts_created_at_idx ON saved_prompts (created_at DESC);
@@ -0,0 +1,6 @@
+-- SQLite can't drop an indexed column
+DROP INDEX saved_prompts_author_id_idx;
+
+ALTER TABLE saved_prompts DROP COLUMN author_id;
+
+DROP TABLE users;
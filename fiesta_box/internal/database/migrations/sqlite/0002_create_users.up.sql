@@ -0,0 +1,14 @@
+CREATE TABLE users (
+	id           TEXT PRIMARY KEY,
+	display_name TEXT NOT NULL,
+	avatar_url   TEXT NOT NULL DEFAULT '',
+	-- SHA-256 of the user's token, never the token itself
+	token_hash   TEXT NOT NULL,
+	-- unix nanoseconds
+	created_at   INTEGER NOT NULL
+);
+
+-- no foreign key, SQLite can't drop a column that is part of one
+ALTER TABLE saved_prompts ADD COLUMN author_id TEXT;
+
+CREATE INDEX saved_prompts_author_id_idx ON saved_prompts (author_id);
@@ -0,0 +1,18 @@
+CREATE TABLE user_games (
+	user_id      TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	game_id      TEXT NOT NULL,
+	room         TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	performed    INTEGER NOT NULL,
+	drank        INTEGER NOT NULL,
+	rank         INTEGER NOT NULL,
+	players      INTEGER NOT NULL,
+	rounds       INTEGER NOT NULL,
+	-- 0 or 1
+	mvp          INTEGER NOT NULL,
+	-- unix nanoseconds
+	completed_at INTEGER NOT NULL,
+	PRIMARY KEY (user_id, game_id)
+);
+
+CREATE INDEX user_games_completed_at_idx ON user_games (user_id, completed_at DESC);
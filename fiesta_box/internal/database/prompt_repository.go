@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"fiesta_box/internal/models/prompts"
+)
+
+var ErrPromptNotFound = errors.New("saved prompt not found")
+
+// PromptRepository stores the prompt library hosts reuse across games.
+type PromptRepository interface {
+	// Create saves the prompt, filling in its ID and CreatedAt. It returns
+	// ErrUserNotFound if the prompt names an author who isn't a user.
+	Create(ctx context.Context, prompt *prompts.SavedPrompt) error
+	// Get returns the saved prompt, or ErrPromptNotFound.
+	Get(ctx context.Context, id string) (*prompts.SavedPrompt, error)
+	// List returns saved prompts, newest first.
+	List(ctx context.Context, limit int, offset int) ([]prompts.SavedPrompt, error)
+	// Search returns the saved prompts matching the query, newest first.
+	Search(ctx context.Context, query prompts.SavedPromptQuery) ([]prompts.SavedPrompt, error)
+	// Delete removes the saved prompt, or returns ErrPromptNotFound.
+	Delete(ctx context.Context, id string) error
+}
+
+type promptRepository struct {
+	db *sql.DB
+	// types scans Postgres arrays, which database/sql can't do by itself
+	types *pgtype.Map
+}
+
+func newPromptRepository(db *sql.DB) *promptRepository {
+	return &promptRepository{db: db, types: pgtype.NewMap()}
+}
+
+func (r *promptRepository) Create(ctx context.Context, prompt *prompts.SavedPrompt) error {
+	if prompt.AuthorID != "" {
+		if _, err := uuid.Parse(prompt.AuthorID); err != nil {
+			return ErrUserNotFound
+		}
+	}
+
+	prompt.ID = uuid.NewString()
+	prompt.Tags = prompts.NormalizeTags(prompt.Tags)
+	prompt.CreatedAt = time.Now().UTC()
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO saved_prompts (id, text, category, tags, author, author_id, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		prompt.ID, prompt.Text, prompt.Category, prompt.Tags, prompt.Author, nullIfEmpty(prompt.AuthorID), prompt.CreatedAt,
+	)
+	if err != nil && prompt.AuthorID != "" && !userExists(ctx, r.db, prompt.AuthorID) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("could not save prompt: %w", err)
+	}
+	return nil
+}
+
+func (r *promptRepository) Get(ctx context.Context, id string) (*prompts.SavedPrompt, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		// not an id we could have handed out
+		return nil, ErrPromptNotFound
+	}
+
+	row := r.db.QueryRowContext(ctx,
+		`SELECT id, text, category, tags, author, author_id, created_at FROM saved_prompts WHERE id = $1`,
+		id,
+	)
+	prompt, err := r.scan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPromptNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get saved prompt %s: %w", id, err)
+	}
+	return prompt, nil
+}
+
+func (r *promptRepository) List(ctx context.Context, limit int, offset int) ([]prompts.SavedPrompt, error) {
+	return r.Search(ctx, prompts.SavedPromptQuery{Limit: limit, Offset: offset})
+}
+
+func (r *promptRepository) Search(ctx context.Context, query prompts.SavedPromptQuery) ([]prompts.SavedPrompt, error) {
+	limit, offset := query.Page()
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, text, category, tags, author, author_id, created_at FROM saved_prompts
+		WHERE ($1 = '' OR text ILIKE '%' || $1 || '%')
+			AND ($2 = '' OR category = $2)
+			AND ($3 = '' OR $3 = ANY(tags))
+			AND ($4 = '' OR author = $4)
+			AND ($5 = '' OR author_id::text = $5)
+		ORDER BY created_at DESC, id
+		LIMIT $6 OFFSET $7`,
+		escapeLike(strings.TrimSpace(query.Text)),
+		strings.TrimSpace(query.Category),
+		strings.ToLower(strings.TrimSpace(query.Tag)),
+		strings.TrimSpace(query.Author),
+		strings.TrimSpace(query.AuthorID),
+		limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not search saved prompts: %w", err)
+	}
+	defer rows.Close()
+
+	found := []prompts.SavedPrompt{}
+	for rows.Next() {
+		prompt, err := r.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("could not read saved prompt: %w", err)
+		}
+		found = append(found, *prompt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not search saved prompts: %w", err)
+	}
+	return found, nil
+}
+
+func (r *promptRepository) Delete(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrPromptNotFound
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM saved_prompts WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("could not delete saved prompt %s: %w", id, err)
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return ErrPromptNotFound
+	}
+	return nil
+}
+
+func (r *promptRepository) scan(row interface{ Scan(...any) error }) (*prompts.SavedPrompt, error) {
+	prompt := &prompts.SavedPrompt{}
+	var authorID sql.NullString
+	err := row.Scan(&prompt.ID, &prompt.Text, &prompt.Category, r.types.SQLScanner(&prompt.Tags), &prompt.Author, &authorID, &prompt.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	prompt.AuthorID = authorID.String
+	if prompt.Tags == nil {
+		prompt.Tags = []string{}
+	}
+	return prompt, nil
+}
+
+// escapeLike stops % and _ in a search from acting as wildcards.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
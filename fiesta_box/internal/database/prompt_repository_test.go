@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/models/users"
+)
+
+// forEachStore runs the test against every store, each migrated from scratch.
+func forEachStore(t *testing.T, test func(t *testing.T, prompts PromptRepository, users UserRepository)) {
+	t.Run(DriverMemory, func(t *testing.T) {
+		users := newMemoryUserRepository()
+		test(t, newMemoryPromptRepository(users), users)
+	})
+
+	for _, d := range testDialects {
+		t.Run(d.name, func(t *testing.T) {
+			db := openTestDB(t, d)
+			migrator, err := newMigrator(db, d)
+			if err != nil {
+				t.Fatalf("could not load migrations: %v", err)
+			}
+			if _, err := migrator.Up(context.Background()); err != nil {
+				t.Fatalf("could not migrate: %v", err)
+			}
+			t.Cleanup(func() {
+				migrator.Down(context.Background(), len(migrator.migrations))
+				db.ExecContext(context.Background(), `DROP TABLE schema_migrations`)
+			})
+
+			test(t, d.prompts(db), d.users(db))
+		})
+	}
+}
+
+// forEachPromptRepository runs the test against the prompt library of every store.
+func forEachPromptRepository(t *testing.T, test func(t *testing.T, repository PromptRepository)) {
+	forEachStore(t, func(t *testing.T, prompts PromptRepository, users UserRepository) {
+		test(t, prompts)
+	})
+}
+
+func TestPromptRepositoryCreateGetDelete(t *testing.T) {
+	forEachPromptRepository(t, testPromptRepositoryCreateGetDelete)
+}
+
+func testPromptRepositoryCreateGetDelete(t *testing.T, repository PromptRepository) {
+	ctx := context.Background()
+
+	prompt := &prompts.SavedPrompt{Text: "Sing the chorus", Category: "music", Tags: []string{" Loud", "loud", "solo"}, Author: "Host"}
+	if err := repository.Create(ctx, prompt); err != nil {
+		t.Fatalf("could not create prompt: %v", err)
+	}
+	if prompt.ID == "" || prompt.CreatedAt.IsZero() {
+		t.Fatalf("expected an id and creation time, got %+v", prompt)
+	}
+
+	got, err := repository.Get(ctx, prompt.ID)
+	if err != nil {
+		t.Fatalf("could not get prompt: %v", err)
+	}
+	if got.Text != prompt.Text || got.Category != "music" || got.Author != "Host" {
+		t.Fatalf("got %+v, want %+v", got, prompt)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "loud" || got.Tags[1] != "solo" {
+		t.Fatalf("expected tags [loud solo], got %v", got.Tags)
+	}
+
+	if err := repository.Delete(ctx, prompt.ID); err != nil {
+		t.Fatalf("could not delete prompt: %v", err)
+	}
+	if _, err := repository.Get(ctx, prompt.ID); !errors.Is(err, ErrPromptNotFound) {
+		t.Fatalf("expected ErrPromptNotFound after delete, got %v", err)
+	}
+	if err := repository.Delete(ctx, prompt.ID); !errors.Is(err, ErrPromptNotFound) {
+		t.Fatalf("expected ErrPromptNotFound deleting twice, got %v", err)
+	}
+	if _, err := repository.Get(ctx, "not-a-uuid"); !errors.Is(err, ErrPromptNotFound) {
+		t.Fatalf("expected ErrPromptNotFound for a malformed id, got %v", err)
+	}
+}
+
+func TestPromptRepositorySearch(t *testing.T) {
+	forEachStore(t, testPromptRepositorySearch)
+}
+
+func testPromptRepositorySearch(t *testing.T, repository PromptRepository, userRepository UserRepository) {
+	ctx := context.Background()
+
+	ana := &users.User{DisplayName: "ana", TokenHash: users.HashToken("secret")}
+	if err := userRepository.Create(ctx, ana); err != nil {
+		t.Fatalf("could not create user: %v", err)
+	}
+
+	for _, prompt := range []*prompts.SavedPrompt{
+		{Text: "Do 10% of a push-up", Category: "dares", Tags: []string{"silly"}, Author: "ana", AuthorID: ana.ID},
+		{Text: "Do a push-up", Category: "dares", Tags: []string{"fitness"}, Author: "ben"},
+		{Text: "Tell a secret", Category: "truths", Tags: []string{"silly"}, Author: "ana"},
+	} {
+		if err := repository.Create(ctx, prompt); err != nil {
+			t.Fatalf("could not create prompt: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query prompts.SavedPromptQuery
+		want  int
+	}{
+		{"everything", prompts.SavedPromptQuery{}, 3},
+		{"text ignores case", prompts.SavedPromptQuery{Text: "PUSH-UP"}, 2},
+		{"percent is literal", prompts.SavedPromptQuery{Text: "10%"}, 1},
+		{"category", prompts.SavedPromptQuery{Category: "truths"}, 1},
+		{"tag", prompts.SavedPromptQuery{Tag: "Silly"}, 2},
+		{"author and tag", prompts.SavedPromptQuery{Author: "ana", Tag: "silly"}, 2},
+		{"author id", prompts.SavedPromptQuery{AuthorID: ana.ID}, 1},
+		{"page", prompts.SavedPromptQuery{Limit: 2, Offset: 2}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := repository.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("could not search: %v", err)
+			}
+			if len(found) != tt.want {
+				t.Fatalf("expected %d prompts, got %d: %+v", tt.want, len(found), found)
+			}
+		})
+	}
+
+	listed, err := repository.List(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("could not list: %v", err)
+	}
+	if len(listed) != 1 || listed[0].Text != "Tell a secret" {
+		t.Fatalf("expected the newest prompt first, got %+v", listed)
+	}
+}
@@ -0,0 +1,241 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/models/users"
+)
+
+// sqlitePromptRepository is the prompt library kept in SQLite. Tags are stored
+// as a JSON array and times as unix nanoseconds.
+type sqlitePromptRepository struct {
+	db *sql.DB
+}
+
+func newSQLitePromptRepository(db *sql.DB) *sqlitePromptRepository {
+	return &sqlitePromptRepository{db: db}
+}
+
+func (r *sqlitePromptRepository) Create(ctx context.Context, prompt *prompts.SavedPrompt) error {
+	// author_id has no foreign key to check it here
+	if prompt.AuthorID != "" && !userExists(ctx, r.db, prompt.AuthorID) {
+		return ErrUserNotFound
+	}
+
+	prompt.ID = uuid.NewString()
+	prompt.Tags = prompts.NormalizeTags(prompt.Tags)
+	prompt.CreatedAt = time.Now().UTC()
+
+	tags, err := json.Marshal(prompt.Tags)
+	if err != nil {
+		return fmt.Errorf("could not save prompt: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO saved_prompts (id, text, category, tags, author, author_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		prompt.ID, prompt.Text, prompt.Category, string(tags), prompt.Author, nullIfEmpty(prompt.AuthorID), prompt.CreatedAt.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not save prompt: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlitePromptRepository) Get(ctx context.Context, id string) (*prompts.SavedPrompt, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT id, text, category, tags, author, author_id, created_at FROM saved_prompts WHERE id = ?`,
+		id,
+	)
+	prompt, err := scanSQLitePrompt(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPromptNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get saved prompt %s: %w", id, err)
+	}
+	return prompt, nil
+}
+
+func (r *sqlitePromptRepository) List(ctx context.Context, limit int, offset int) ([]prompts.SavedPrompt, error) {
+	return r.Search(ctx, prompts.SavedPromptQuery{Limit: limit, Offset: offset})
+}
+
+func (r *sqlitePromptRepository) Search(ctx context.Context, query prompts.SavedPromptQuery) ([]prompts.SavedPrompt, error) {
+	text := escapeLike(strings.TrimSpace(query.Text))
+	category := strings.TrimSpace(query.Category)
+	tag := strings.ToLower(strings.TrimSpace(query.Tag))
+	author := strings.TrimSpace(query.Author)
+	authorID := strings.TrimSpace(query.AuthorID)
+	limit, offset := query.Page()
+
+	// LIKE already ignores case for ASCII in SQLite
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, text, category, tags, author, author_id, created_at FROM saved_prompts
+		WHERE (? = '' OR text LIKE '%' || ? || '%' ESCAPE '\')
+			AND (? = '' OR category = ?)
+			AND (? = '' OR EXISTS (SELECT 1 FROM json_each(saved_prompts.tags) WHERE json_each.value = ?))
+			AND (? = '' OR author = ?)
+			AND (? = '' OR author_id = ?)
+		ORDER BY created_at DESC, id
+		LIMIT ? OFFSET ?`,
+		text, text, category, category, tag, tag, author, author, authorID, authorID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not search saved prompts: %w", err)
+	}
+	defer rows.Close()
+
+	found := []prompts.SavedPrompt{}
+	for rows.Next() {
+		prompt, err := scanSQLitePrompt(rows)
+		if err != nil {
+			return nil, fmt.Errorf("could not read saved prompt: %w", err)
+		}
+		found = append(found, *prompt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not search saved prompts: %w", err)
+	}
+	return found, nil
+}
+
+func (r *sqlitePromptRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM saved_prompts WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("could not delete saved prompt %s: %w", id, err)
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return ErrPromptNotFound
+	}
+	return nil
+}
+
+func scanSQLitePrompt(row interface{ Scan(...any) error }) (*prompts.SavedPrompt, error) {
+	prompt := &prompts.SavedPrompt{}
+	var tags string
+	var authorID sql.NullString
+	var createdAt int64
+	if err := row.Scan(&prompt.ID, &prompt.Text, &prompt.Category, &tags, &prompt.Author, &authorID, &createdAt); err != nil {
+		return nil, err
+	}
+	prompt.AuthorID = authorID.String
+	if err := json.Unmarshal([]byte(tags), &prompt.Tags); err != nil {
+		return nil, fmt.Errorf("tags of saved prompt %s are not valid JSON: %w", prompt.ID, err)
+	}
+	if prompt.Tags == nil {
+		prompt.Tags = []string{}
+	}
+	prompt.CreatedAt = time.Unix(0, createdAt).UTC()
+	return prompt, nil
+}
+
+// sqliteUserRepository keeps users in SQLite, with times as unix nanoseconds.
+type sqliteUserRepository struct {
+	db *sql.DB
+}
+
+func newSQLiteUserRepository(db *sql.DB) *sqliteUserRepository {
+	return &sqliteUserRepository{db: db}
+}
+
+func (r *sqliteUserRepository) Create(ctx context.Context, user *users.User) error {
+	user.ID = uuid.NewString()
+	user.CreatedAt = time.Now().UTC()
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO users (id, display_name, avatar_url, token_hash, created_at) VALUES (?, ?, ?, ?, ?)`,
+		user.ID, user.DisplayName, user.AvatarURL, user.TokenHash, user.CreatedAt.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not save user: %w", err)
+	}
+	return nil
+}
+
+func (r *sqliteUserRepository) Get(ctx context.Context, id string) (*users.User, error) {
+	user := &users.User{}
+	var createdAt int64
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, display_name, avatar_url, token_hash, created_at FROM users WHERE id = ?`,
+		id,
+	).Scan(&user.ID, &user.DisplayName, &user.AvatarURL, &user.TokenHash, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get user %s: %w", id, err)
+	}
+	user.CreatedAt = time.Unix(0, createdAt).UTC()
+	return user, nil
+}
+
+func (r *sqliteUserRepository) RecordGame(ctx context.Context, userID string, record users.GameRecord) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO user_games (user_id, game_id, room, name, performed, drank, rank, players, rounds, mvp, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, game_id) DO NOTHING`,
+		userID, record.GameID, record.Room, record.Name, record.Performed, record.Drank,
+		record.Rank, record.Players, record.Rounds, record.MVP, record.CompletedAt.UnixNano(),
+	)
+	if err != nil && !userExists(ctx, r.db, userID) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("could not record game %s for user %s: %w", record.GameID, userID, err)
+	}
+	return nil
+}
+
+func (r *sqliteUserRepository) History(ctx context.Context, userID string, limit int, offset int) ([]users.GameRecord, error) {
+	limit, offset = users.HistoryPage(limit, offset)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT game_id, room, name, performed, drank, rank, players, rounds, mvp, completed_at FROM user_games
+		WHERE user_id = ?
+		ORDER BY completed_at DESC, game_id
+		LIMIT ? OFFSET ?`,
+		userID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get history of user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	history := []users.GameRecord{}
+	for rows.Next() {
+		var record users.GameRecord
+		var completedAt int64
+		err := rows.Scan(&record.GameID, &record.Room, &record.Name, &record.Performed, &record.Drank,
+			&record.Rank, &record.Players, &record.Rounds, &record.MVP, &completedAt)
+		if err != nil {
+			return nil, fmt.Errorf("could not read history of user %s: %w", userID, err)
+		}
+		record.CompletedAt = time.Unix(0, completedAt).UTC()
+		history = append(history, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not get history of user %s: %w", userID, err)
+	}
+	return history, nil
+}
+
+func (r *sqliteUserRepository) Totals(ctx context.Context, userID string) (users.Totals, error) {
+	var totals users.Totals
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(performed), 0), COALESCE(SUM(drank), 0), COALESCE(SUM(mvp), 0)
+		FROM user_games WHERE user_id = ?`,
+		userID,
+	).Scan(&totals.Games, &totals.Performed, &totals.Drank, &totals.MVPs)
+	if err != nil {
+		return totals, fmt.Errorf("could not add up the games of user %s: %w", userID, err)
+	}
+	return totals, nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/models/users"
+)
+
+// TestStoresAgree pins down how every store answers for ids it never handed
+// out, so that switching drivers doesn't change what callers see.
+func TestStoresAgree(t *testing.T) {
+	forEachStore(t, testStoresAgree)
+}
+
+func testStoresAgree(t *testing.T, promptRepository PromptRepository, userRepository UserRepository) {
+	ctx := context.Background()
+
+	// a well-formed id nobody has, and one no store could have handed out
+	unknownIDs := []string{"7d3f0c59-4a2b-4c1e-9a53-2f4f1b9e0d11", "not-a-uuid"}
+	record := users.GameRecord{GameID: "0b7e5f0a-1c1d-4c52-8d38-6f1f3f5b0a01", Room: "ABCD", Name: "Ana", Rank: 1, Players: 3, Rounds: 1, CompletedAt: time.Now().UTC()}
+
+	for _, id := range unknownIDs {
+		t.Run(id, func(t *testing.T) {
+			if _, err := promptRepository.Get(ctx, id); !errors.Is(err, ErrPromptNotFound) {
+				t.Errorf("getting the prompt: expected ErrPromptNotFound, got %v", err)
+			}
+			if err := promptRepository.Delete(ctx, id); !errors.Is(err, ErrPromptNotFound) {
+				t.Errorf("deleting the prompt: expected ErrPromptNotFound, got %v", err)
+			}
+			if found, err := promptRepository.Search(ctx, prompts.SavedPromptQuery{AuthorID: id}); err != nil || len(found) != 0 {
+				t.Errorf("searching by author: expected nothing, got %+v, %v", found, err)
+			}
+			prompt := &prompts.SavedPrompt{Text: "Tell a secret", Author: "Ana", AuthorID: id}
+			if err := promptRepository.Create(ctx, prompt); !errors.Is(err, ErrUserNotFound) {
+				t.Errorf("saving a prompt by the user: expected ErrUserNotFound, got %v", err)
+			}
+
+			if _, err := userRepository.Get(ctx, id); !errors.Is(err, ErrUserNotFound) {
+				t.Errorf("getting the user: expected ErrUserNotFound, got %v", err)
+			}
+			if err := userRepository.RecordGame(ctx, id, record); !errors.Is(err, ErrUserNotFound) {
+				t.Errorf("recording a game: expected ErrUserNotFound, got %v", err)
+			}
+			if history, err := userRepository.History(ctx, id, 0, 0); err != nil || len(history) != 0 {
+				t.Errorf("getting the history: expected none, got %+v, %v", history, err)
+			}
+			if totals, err := userRepository.Totals(ctx, id); err != nil || totals != (users.Totals{}) {
+				t.Errorf("adding up games: expected nothing, got %+v, %v", totals, err)
+			}
+		})
+	}
+
+	// nothing refused above may have been kept
+	if found, err := promptRepository.List(ctx, 0, 0); err != nil || len(found) != 0 {
+		t.Fatalf("expected no saved prompts, got %+v, %v", found, err)
+	}
+}
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fiesta_box/internal/models/users"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+// UserRepository stores the players who keep an identity across games.
+type UserRepository interface {
+	// Create saves the user, filling in its ID and CreatedAt.
+	Create(ctx context.Context, user *users.User) error
+	// Get returns the user, or ErrUserNotFound.
+	Get(ctx context.Context, id string) (*users.User, error)
+	// RecordGame adds a finished game to the user's history, or returns
+	// ErrUserNotFound. Recording the same game twice keeps the first record.
+	RecordGame(ctx context.Context, userID string, record users.GameRecord) error
+	// History returns the games the user finished, newest first.
+	History(ctx context.Context, userID string, limit int, offset int) ([]users.GameRecord, error)
+	// Totals adds up every game the user finished.
+	Totals(ctx context.Context, userID string) (users.Totals, error)
+}
+
+type userRepository struct {
+	db *sql.DB
+}
+
+func newUserRepository(db *sql.DB) *userRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) Create(ctx context.Context, user *users.User) error {
+	user.ID = uuid.NewString()
+	user.CreatedAt = time.Now().UTC()
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO users (id, display_name, avatar_url, token_hash, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		user.ID, user.DisplayName, user.AvatarURL, user.TokenHash, user.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("could not save user: %w", err)
+	}
+	return nil
+}
+
+func (r *userRepository) Get(ctx context.Context, id string) (*users.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		// not an id we could have handed out
+		return nil, ErrUserNotFound
+	}
+
+	user := &users.User{}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, display_name, avatar_url, token_hash, created_at FROM users WHERE id = $1`,
+		id,
+	).Scan(&user.ID, &user.DisplayName, &user.AvatarURL, &user.TokenHash, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get user %s: %w", id, err)
+	}
+	return user, nil
+}
+
+func (r *userRepository) RecordGame(ctx context.Context, userID string, record users.GameRecord) error {
+	if _, err := uuid.Parse(userID); err != nil {
+		return ErrUserNotFound
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO user_games (user_id, game_id, room, name, performed, drank, rank, players, rounds, mvp, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (user_id, game_id) DO NOTHING`,
+		userID, record.GameID, record.Room, record.Name, record.Performed, record.Drank,
+		record.Rank, record.Players, record.Rounds, record.MVP, record.CompletedAt.UTC(),
+	)
+	if err != nil && !userExists(ctx, r.db, userID) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("could not record game %s for user %s: %w", record.GameID, userID, err)
+	}
+	return nil
+}
+
+func (r *userRepository) History(ctx context.Context, userID string, limit int, offset int) ([]users.GameRecord, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return []users.GameRecord{}, nil
+	}
+
+	limit, offset = users.HistoryPage(limit, offset)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT game_id, room, name, performed, drank, rank, players, rounds, mvp, completed_at FROM user_games
+		WHERE user_id = $1
+		ORDER BY completed_at DESC, game_id
+		LIMIT $2 OFFSET $3`,
+		userID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get history of user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	history := []users.GameRecord{}
+	for rows.Next() {
+		var record users.GameRecord
+		err := rows.Scan(&record.GameID, &record.Room, &record.Name, &record.Performed, &record.Drank,
+			&record.Rank, &record.Players, &record.Rounds, &record.MVP, &record.CompletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("could not read history of user %s: %w", userID, err)
+		}
+		history = append(history, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not get history of user %s: %w", userID, err)
+	}
+	return history, nil
+}
+
+func (r *userRepository) Totals(ctx context.Context, userID string) (users.Totals, error) {
+	var totals users.Totals
+	if _, err := uuid.Parse(userID); err != nil {
+		return totals, nil
+	}
+
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(performed), 0), COALESCE(SUM(drank), 0), COUNT(*) FILTER (WHERE mvp)
+		FROM user_games WHERE user_id = $1`,
+		userID,
+	).Scan(&totals.Games, &totals.Performed, &totals.Drank, &totals.MVPs)
+	if err != nil {
+		return totals, fmt.Errorf("could not add up the games of user %s: %w", userID, err)
+	}
+	return totals, nil
+}
+
+// userExists tells an unknown user apart from any other reason a write naming
+// one was refused, since each driver reports a broken foreign key its own way.
+// SQLite reads $1 like Postgres does.
+func userExists(ctx context.Context, db *sql.DB, id string) bool {
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, id).Scan(&exists)
+	// if we can't tell, let the caller report its own error
+	return err != nil || exists
+}
+
+// nullIfEmpty stores an optional id as NULL rather than "".
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/models/users"
+)
+
+func TestUserRepositoryCreateGet(t *testing.T) {
+	forEachStore(t, testUserRepositoryCreateGet)
+}
+
+func testUserRepositoryCreateGet(t *testing.T, promptRepository PromptRepository, repository UserRepository) {
+	ctx := context.Background()
+
+	user := &users.User{DisplayName: "Ana", AvatarURL: "https://example.com/ana.png", TokenHash: users.HashToken("secret")}
+	if err := repository.Create(ctx, user); err != nil {
+		t.Fatalf("could not create user: %v", err)
+	}
+	if user.ID == "" || user.CreatedAt.IsZero() {
+		t.Fatalf("expected an id and creation time, got %+v", user)
+	}
+
+	got, err := repository.Get(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("could not get user: %v", err)
+	}
+	if got.DisplayName != "Ana" || got.AvatarURL != user.AvatarURL || !got.HasToken("secret") || got.HasToken("guess") {
+		t.Fatalf("got %+v, want %+v", got, user)
+	}
+	// Postgres keeps microseconds
+	if got.CreatedAt.Sub(user.CreatedAt).Abs() > time.Millisecond {
+		t.Fatalf("got created at %s, want %s", got.CreatedAt, user.CreatedAt)
+	}
+
+	// saved prompts remember who wrote them
+	prompt := &prompts.SavedPrompt{Text: "Tell a secret", Author: got.DisplayName, AuthorID: got.ID}
+	if err := promptRepository.Create(ctx, prompt); err != nil {
+		t.Fatalf("could not create prompt: %v", err)
+	}
+	saved, err := promptRepository.Get(ctx, prompt.ID)
+	if err != nil || saved.AuthorID != user.ID {
+		t.Fatalf("expected the prompt to keep author %s, got %+v, %v", user.ID, saved, err)
+	}
+
+	for _, id := range []string{"7d3f0c59-4a2b-4c1e-9a53-2f4f1b9e0d11", "not-a-uuid"} {
+		if _, err := repository.Get(ctx, id); !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound for %s, got %v", id, err)
+		}
+	}
+}
+
+func TestUserRepositoryHistory(t *testing.T) {
+	forEachStore(t, testUserRepositoryHistory)
+}
+
+func testUserRepositoryHistory(t *testing.T, promptRepository PromptRepository, repository UserRepository) {
+	ctx := context.Background()
+
+	user := &users.User{DisplayName: "Ana", TokenHash: users.HashToken("secret")}
+	if err := repository.Create(ctx, user); err != nil {
+		t.Fatalf("could not create user: %v", err)
+	}
+
+	start := time.Now().UTC().Truncate(time.Millisecond)
+	records := []users.GameRecord{
+		{GameID: "0b7e5f0a-1c1d-4c52-8d38-6f1f3f5b0a01", Room: "ABCD", Name: "Ana", Performed: 3, Drank: 1, Rank: 1, Players: 4, Rounds: 2, MVP: true, CompletedAt: start},
+		{GameID: "0b7e5f0a-1c1d-4c52-8d38-6f1f3f5b0a02", Room: "EFGH", Name: "Ana B", Performed: 1, Drank: 2, Rank: 3, Players: 3, Rounds: 1, CompletedAt: start.Add(time.Hour)},
+	}
+	for _, record := range append(records, records[0]) {
+		if err := repository.RecordGame(ctx, user.ID, record); err != nil {
+			t.Fatalf("could not record game: %v", err)
+		}
+	}
+
+	history, err := repository.History(ctx, user.ID, 0, 0)
+	if err != nil {
+		t.Fatalf("could not get history: %v", err)
+	}
+	if len(history) != 2 || history[0].Room != "EFGH" || !history[1].MVP || !history[1].CompletedAt.Equal(start) {
+		t.Fatalf("expected both games newest first, recorded once, got %+v", history)
+	}
+	if page, err := repository.History(ctx, user.ID, 1, 1); err != nil || len(page) != 1 || page[0].Room != "ABCD" {
+		t.Fatalf("expected the second page to hold the oldest game, got %+v, %v", page, err)
+	}
+
+	totals, err := repository.Totals(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("could not add up games: %v", err)
+	}
+	if totals != (users.Totals{Games: 2, Performed: 4, Drank: 3, MVPs: 1}) {
+		t.Fatalf("unexpected totals %+v", totals)
+	}
+
+	if totals, err := repository.Totals(ctx, "not-a-uuid"); err != nil || totals.Games != 0 {
+		t.Fatalf("expected no games for an unknown user, got %+v, %v", totals, err)
+	}
+}
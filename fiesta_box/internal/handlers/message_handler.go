@@ -1,197 +1,571 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 
-	"github.com/gorilla/websocket"
-
+	"fiesta_box/internal/database"
 	"fiesta_box/internal/models/games"
 	"fiesta_box/internal/models/messages"
+	"fiesta_box/internal/models/prompts"
 	"fiesta_box/internal/models/responses"
 	"fiesta_box/internal/services"
+	"fiesta_box/internal/sockets"
 )
 
 
 type HandlerFuncArgs struct {
+	// Context is cancelled once the reply is no longer wanted
+	Context context.Context
 	Message messages.Message
-	GameService *services.GameService
-	Client *websocket.Conn
+	// Payload is the message content decoded by the decoder registered for its type
+	Payload messages.Payload
+	GameService services.GameServiceInterface
+	Client *sockets.Client
+	// Session holds what the server remembers about this connection between messages
+	Session *Session
+	// SavedPrompts is the prompt library, nil when there is no database to keep it in
+	SavedPrompts database.PromptRepository
+	// Users signs players in to their accounts, nil when there is no database
+	Users *services.UserService
 }
 
 
 type HandlerFunc func(HandlerFuncArgs) (responses.SocketResponse, error)
 
-var HandlerRegistry = make(map[messages.MessageType]HandlerFunc)
+// allProtocols registers a handler or payload for every protocol version.
+const allProtocols messages.ProtocolVersion = 0
+
+var HandlerRegistry = make(map[messages.MessageType]map[messages.ProtocolVersion]HandlerFunc)
+
+var PayloadRegistry = make(map[messages.MessageType]map[messages.ProtocolVersion]messages.PayloadDecoder)
 
+// RegisterHandler registers the handler for every protocol version.
 func RegisterHandler(messageType messages.MessageType, handler HandlerFunc) {
-	HandlerRegistry[messageType] = handler
+	RegisterVersionedHandler(allProtocols, messageType, handler)
+}
+
+// RegisterVersionedHandler registers the handler for the protocol version and
+// every later one, until a later version registers a handler of its own.
+func RegisterVersionedHandler(version messages.ProtocolVersion, messageType messages.MessageType, handler HandlerFunc) {
+	if HandlerRegistry[messageType] == nil {
+		HandlerRegistry[messageType] = map[messages.ProtocolVersion]HandlerFunc{}
+	}
+	HandlerRegistry[messageType][version] = handler
+}
+
+// RegisterPayload registers the payload decoder for every protocol version.
+func RegisterPayload(messageType messages.MessageType, decoder messages.PayloadDecoder) {
+	RegisterVersionedPayload(allProtocols, messageType, decoder)
 }
 
+// RegisterVersionedPayload registers the payload decoder for the protocol
+// version and every later one, until a later version registers its own.
+func RegisterVersionedPayload(version messages.ProtocolVersion, messageType messages.MessageType, decoder messages.PayloadDecoder) {
+	if PayloadRegistry[messageType] == nil {
+		PayloadRegistry[messageType] = map[messages.ProtocolVersion]messages.PayloadDecoder{}
+	}
+	PayloadRegistry[messageType][version] = decoder
+}
+
+// forProtocol picks what was registered for the newest version no later than
+// the one the connection speaks.
+func forProtocol[T any](registered map[messages.ProtocolVersion]T, version messages.ProtocolVersion) (T, bool) {
+	var found T
+	best, ok := allProtocols, false
+	for v, value := range registered {
+		if v <= version && (!ok || v > best) {
+			found, best, ok = value, v, true
+		}
+	}
+	return found, ok
+}
+
+// HandleMessage runs the handler registered for the message and tags the
+// reply with the requestId the client sent, so it can tell which of its
+// messages the reply answers.
 func HandleMessage(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	handler, ok := HandlerRegistry[args.Message.Type]
+	response, err := dispatch(args)
+	if err != nil {
+		response = responses.SocketResponse{
+			Status: responses.Error,
+			Message: "Could not handle message",
+			Error: &responses.SocketError{
+				Code: responses.CodeInternal,
+				Message: "something went wrong on the server",
+			},
+		}
+	}
+
+	response.Kind = responses.KindReply
+	response.RequestID = args.Message.RequestID
+	return response, err
+}
+
+func dispatch(args HandlerFuncArgs) (responses.SocketResponse, error) {
+	version := args.Session.protocol()
+
+	handler, ok := forProtocol(HandlerRegistry[args.Message.Type], version)
 	if !ok {
 		return responses.SocketResponse{
 			Status: responses.UnknownMessageType,
 			Message: "Unknown message type",
+			Error: &responses.SocketError{
+				Code: responses.CodeUnknownMessageType,
+				Message: fmt.Sprintf("%q is not a known message type", args.Message.Type),
+			},
 		}, nil
 	}
+
+	// handlers only ever see content that decoded and validated
+	if decode, ok := forProtocol(PayloadRegistry[args.Message.Type], version); ok {
+		payload, err := decode(args.Message.Content)
+		if err != nil {
+			var invalid *messages.ValidationError
+			if !errors.As(err, &invalid) {
+				return responses.SocketResponse{}, err
+			}
+			invalid.Type = args.Message.Type
+			return invalidPayload(invalid), nil
+		}
+		args.Payload = payload
+	}
+
 	return handler(args)
 }
 
+// invalidPayload tells the client everything that is wrong with its message.
+func invalidPayload(invalid *messages.ValidationError) responses.SocketResponse {
+	return responses.SocketResponse{
+		Status: responses.InvalidMessage,
+		Message: invalid.Error(),
+		Error: &responses.SocketError{
+			Code: responses.CodeInvalidPayload,
+			Message: invalid.Error(),
+			Details: invalid,
+		},
+	}
+}
+
+// MalformedMessage replies to a frame that is not a JSON message at all. There
+// is no requestId to echo, so the client can only tell it from the error code.
+func MalformedMessage(err error) responses.SocketResponse {
+	message := fmt.Sprintf("Message is not valid JSON: %v", err)
+	return responses.SocketResponse{
+		Kind: responses.KindReply,
+		Status: responses.InvalidMessage,
+		Message: message,
+		Error: &responses.SocketError{
+			Code: responses.CodeInvalidPayload,
+			Message: message,
+		},
+	}
+}
+
+// invalidField reports a field the handler itself rejected in the same shape
+// as a payload that failed validation.
+func invalidField(args HandlerFuncArgs, field string, err error) responses.SocketResponse {
+	return invalidPayload(&messages.ValidationError{
+		Type: args.Message.Type,
+		Fields: []messages.FieldError{{Field: field, Problem: err.Error()}},
+	})
+}
+
 func StartGameHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	// TODO: unmarshal content JSON string to get game id
+	payload := args.Payload.(*messages.RoomPayload)
+
+	value := payload.Room
+
+	if err := args.GameService.StartGame(args.Context, args.Client, value); err != nil {
+		return serviceError(err, fmt.Sprintf("Could not start game %s: %s", value, reason(err))), nil
+	}
 
-	// TODO: Start game logic
 	response := responses.SocketResponse{
 		Status: responses.Success,
-		Message: "Started game!",
+		Message: fmt.Sprintf("Started game %s", value),
 	}
 	return response, nil
 }
 
+// serviceError builds the reply for an error returned by GameService.
+func serviceError(err error, message string) responses.SocketResponse {
+	return responses.SocketResponse{
+		Status: serviceErrorStatus(err),
+		Message: message,
+		Error: &responses.SocketError{
+			Code: errorCode(err),
+			Message: reason(err),
+		},
+	}
+}
+
+// serviceErrorStatus picks the response status for an error returned by GameService.
+func serviceErrorStatus(err error) responses.StatusCode {
+	switch {
+	case errors.Is(err, services.ErrGameNotFound), errors.Is(err, services.ErrNoRoomCodes):
+		return responses.Error
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return responses.Error
+	case errors.Is(err, database.ErrUnavailable):
+		return responses.Error
+	default:
+		return responses.InvalidMessage
+	}
+}
+
+// errorCodes maps the errors GameService returns to the codes clients see.
+var errorCodes = []struct {
+	err error
+	code responses.ErrorCode
+}{
+	{services.ErrGameNotFound, responses.CodeRoomNotFound},
+	{services.ErrRoomFull, responses.CodeRoomFull},
+	{services.ErrGameAlreadyStarted, responses.CodeGameStarted},
+	{services.ErrAlreadyInGame, responses.CodeAlreadyJoined},
+	{services.ErrNotInGame, responses.CodeNotInGame},
+	{services.ErrNotMaster, responses.CodeNotMaster},
+	{services.ErrWrongPhase, responses.CodeWrongPhase},
+	{services.ErrNotEnoughPlayers, responses.CodeNotEnoughPlayers},
+	{services.ErrTooManyPlayers, responses.CodeTooManyPlayers},
+	{services.ErrPlayerNotFound, responses.CodePlayerNotFound},
+	{services.ErrPlayerDisconnected, responses.CodePlayerDisconnected},
+	{services.ErrNameTaken, responses.CodeNameTaken},
+	{services.ErrInvalidName, responses.CodeInvalidPayload},
+	{services.ErrInvalidPrompt, responses.CodeInvalidPayload},
+	{services.ErrInvalidPromptCount, responses.CodeInvalidPayload},
+	{services.ErrInvalidRoundCount, responses.CodeInvalidPayload},
+	{services.ErrPromptQuotaReached, responses.CodePromptQuotaReached},
+	{services.ErrPromptNotHeld, responses.CodePromptNotHeld},
+	{services.ErrWaitForRound, responses.CodeWaitForRound},
+	{services.ErrNoPromptsLeft, responses.CodeNoPromptsLeft},
+	{services.ErrInvalidResumeToken, responses.CodeInvalidResumeToken},
+	{services.ErrNoRoomCodes, responses.CodeNoRoomCodes},
+	{database.ErrPromptNotFound, responses.CodeSavedPromptNotFound},
+	{database.ErrUserNotFound, responses.CodeUserNotFound},
+	{services.ErrInvalidUserToken, responses.CodeInvalidUserToken},
+	{database.ErrUnavailable, responses.CodeUnavailable},
+	{context.DeadlineExceeded, responses.CodeTimeout},
+}
+
+// errorCode picks the machine-readable code for an error returned by GameService.
+func errorCode(err error) responses.ErrorCode {
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			return known.code
+		}
+	}
+	return responses.CodeInternal
+}
+
+// reason is the part of a GameService error that is meant for the player.
+func reason(err error) string {
+	if inner := errors.Unwrap(err); inner != nil {
+		return inner.Error()
+	}
+	return err.Error()
+}
+
 func TransferMasterHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	// TODO: unmarshal content JSON string to get game id
+	payload := args.Payload.(*messages.TransferMasterPayload)
+
+	room := payload.Room
+	userID := payload.UserID
+
+	if err := args.GameService.TransferMaster(args.Context, args.Client, room, userID); err != nil {
+		return serviceError(err, fmt.Sprintf("Could not transfer master of game %s: %s", room, reason(err))), nil
+	}
 
-	// TODO: Transfer master logic
 	response := responses.SocketResponse{
 		Status: responses.Success,
-		Message: "Transferred master!",
+		Message: fmt.Sprintf("Transferred master of game %s to %s", room, userID),
 	}
 	return response, nil
 }
 
 func ConfigurePromptHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	// TODO: unmarshal content JSON string to get game id, prompt count
+	payload := args.Payload.(*messages.PromptCountPayload)
+
+	room := payload.Room
+	count := *payload.Count
+
+	if err := args.GameService.ConfigurePromptCount(args.Context, args.Client, room, count); err != nil {
+		return serviceError(err, fmt.Sprintf("Could not configure prompt count: %s", reason(err))), nil
+	}
 
-	// TODO: Configure prompt logic
 	response := responses.SocketResponse{
 		Status: responses.Success,
-		Message: "Configured prompt count to be N.",
+		Message: fmt.Sprintf("Configured prompt count to be %d.", count),
 	}
 	return response, nil
 }
 
-func UseSavedPromptHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	// TODO: unmarshal content JSON string to get game id, prompt id
+func ConfigureRoundCountHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
+	payload := args.Payload.(*messages.RoundCountPayload)
 
-	// TODO: Use saved prompt logic
+	room := payload.Room
+	rounds := *payload.Rounds
+
+	if err := args.GameService.ConfigureRoundCount(args.Context, args.Client, room, rounds); err != nil {
+		return serviceError(err, fmt.Sprintf("Could not configure round count: %s", reason(err))), nil
+	}
 
 	response := responses.SocketResponse{
 		Status: responses.Success,
-		Message: "Used saved prompt X.",
+		Message: fmt.Sprintf("Configured round count to be %d.", rounds),
 	}
 	return response, nil
 }
 
-func WritePromptHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	// TODO: unmarshal content JSON string to get game id, prompt string
+func GetResultsHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
+	payload := args.Payload.(*messages.RoomPayload)
+
+	room := payload.Room
 
-	// TODO: Write prompt logic; each prompt should have a uid
+	results, err := args.GameService.Results(args.Context, room)
+	if err != nil {
+		return serviceError(err, fmt.Sprintf("Could not get results: %s", reason(err))), nil
+	}
 
 	response := responses.SocketResponse{
 		Status: responses.Success,
-		Message: "Wrote prompt X.",
+		Message: fmt.Sprintf("Results for game %s", room),
+		Content: results,
 	}
 	return response, nil
 }
 
-func ReceivePromptHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	// TODO: unmarshal content JSON string to get game id
+func UseSavedPromptHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
+	payload := args.Payload.(*messages.PromptPayload)
+
+	room := payload.Room
+
+	saved, err := savedPrompt(args, payload.PromptID)
+	if err != nil {
+		return serviceError(err, fmt.Sprintf("Could not use saved prompt: %s", reason(err))), nil
+	}
+
+	text, err := services.NormalizePrompt(saved.Text)
+	if err != nil {
+		return serviceError(err, fmt.Sprintf("Could not use saved prompt: %s", err)), nil
+	}
+	saved.Text = text
 
-	// TODO: Receive prompt logic
+	prompt, err := args.GameService.UseSavedPrompt(args.Context, args.Client, room, saved)
+	if err != nil {
+		return serviceError(err, fmt.Sprintf("Could not use saved prompt: %s", reason(err))), nil
+	}
 
 	response := responses.SocketResponse{
 		Status: responses.Success,
-		Message: "Received prompt X.",
+		Message: fmt.Sprintf("Used saved prompt %s.", saved.ID),
+		Content: map[string]interface{}{
+			"promptID": prompt.ID,
+			"savedPromptID": saved.ID,
+		},
 	}
 	return response, nil
 }
 
-func PerformPromptHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	// TODO: unmarshal content JSON string to get game id, prompt id
+// savedPrompt looks the prompt up in the library. Lookups that fail for any
+// reason other than the prompt not existing are reported as the library being
+// unavailable.
+func savedPrompt(args HandlerFuncArgs, id string) (*prompts.SavedPrompt, error) {
+	if args.SavedPrompts == nil {
+		return nil, fmt.Errorf("saved prompt %s can't be loaded: %w", id, database.ErrUnavailable)
+	}
+
+	saved, err := args.SavedPrompts.Get(args.Context, id)
+	switch {
+	case errors.Is(err, database.ErrPromptNotFound):
+		return nil, fmt.Errorf("saved prompt %s does not exist: %w", id, err)
+	case err != nil:
+		log.Printf("Could not load saved prompt %s: %v", id, err)
+		return nil, fmt.Errorf("saved prompt %s can't be loaded: %w", id, database.ErrUnavailable)
+	}
+	return saved, nil
+}
+
+func WritePromptHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
+	payload := args.Payload.(*messages.WritePromptPayload)
 
-	// TODO: Perform prompt logic
+	room := payload.Room
+
+	text, err := services.NormalizePrompt(payload.Prompt)
+	if err != nil {
+		return invalidField(args, "prompt", err), nil
+	}
+
+	prompt, err := args.GameService.WritePrompt(args.Context, args.Client, room, text)
+	if err != nil {
+		return serviceError(err, fmt.Sprintf("Could not write prompt: %s", reason(err))), nil
+	}
 
 	response := responses.SocketResponse{
 		Status: responses.Success,
-		Message: "Performed prompt X.",
+		Message: "Wrote prompt.",
+		Content: map[string]interface{}{
+			"promptID": prompt.ID,
+		},
 	}
 	return response, nil
 }
 
+func ReceivePromptHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
+	payload := args.Payload.(*messages.RoomPayload)
+
+	room := payload.Room
+
+	result, err := args.GameService.ReceivePrompt(args.Context, args.Client, room)
+	if err != nil {
+		return serviceError(err, fmt.Sprintf("Could not receive prompt: %s", reason(err))), nil
+	}
+
+	response := responses.SocketResponse{
+		Status: responses.Success,
+		Message: fmt.Sprintf("Received prompt %s.", result.Prompt.ID),
+		Content: map[string]interface{}{
+			"promptID": result.Prompt.ID,
+			"prompt": result.Prompt.Text,
+			"round": result.Round,
+		},
+	}
+	return response, nil
+}
+
+func PerformPromptHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
+	return resolvePrompt(args, prompts.Performed)
+}
+
 func DrinkForPromptHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	// TODO: unmarshal content JSON string to get game id, prompt id
+	return resolvePrompt(args, prompts.Drank)
+}
 
-	// TODO: Drink for prompt logic
+// resolvePrompt records that the sender performed or drank for the prompt they hold.
+func resolvePrompt(args HandlerFuncArgs, resolution prompts.Resolution) (responses.SocketResponse, error) {
+	payload := args.Payload.(*messages.PromptPayload)
+
+	room := payload.Room
+	promptID := payload.PromptID
+
+	if err := args.GameService.ResolvePrompt(args.Context, args.Client, room, promptID, resolution); err != nil {
+		return serviceError(err, fmt.Sprintf("Could not resolve prompt %s: %s", promptID, reason(err))), nil
+	}
+
+	message := fmt.Sprintf("Performed prompt %s.", promptID)
+	if resolution == prompts.Drank {
+		message = fmt.Sprintf("Drank for prompt %s.", promptID)
+	}
 
 	response := responses.SocketResponse{
 		Status: responses.Success,
-		Message: "Drank for prompt X.",
+		Message: message,
 	}
 	return response, nil
 }
 
 func ChangePlayerNameHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	// TODO: unmarshal content JSON string to get game id, player name
+	payload := args.Payload.(*messages.ChangePlayerNamePayload)
 
-	// TODO: Change player name logic	
+	room := payload.Room
+
+	name, err := services.NormalizeName(payload.Name)
+	if err != nil {
+		return invalidField(args, "name", err), nil
+	}
+
+	if err := args.GameService.ChangePlayerName(args.Context, args.Client, room, name); err != nil {
+		return serviceError(err, fmt.Sprintf("Could not change name to %s: %s", name, reason(err))), nil
+	}
 
 	response := responses.SocketResponse{
 		Status: responses.Success,
-		Message: "Changed player name to X.",
+		Message: fmt.Sprintf("Changed player name to %s", name),
 	}
 	return response, nil
 }
 
-func JoinGameHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	done := make(chan bool)
+// optionalName validates the optional name field of a message.
+// An absent name is returned as "" so the game service picks a default.
+func optionalName(args HandlerFuncArgs, value string) (string, *responses.SocketResponse) {
+	if value == "" {
+		return "", nil
+	}
 
-	value, exists := args.Message.Content["room"]
-	if !exists {
-		return responses.SocketResponse{
-			Status: responses.InvalidMessage,
-			Message: "Invalid message. Missing room field.",
-		}, nil
+	name, err := services.NormalizeName(value)
+	if err != nil {
+		invalid := invalidField(args, "name", err)
+		return "", &invalid
+	}
+	return name, nil
+}
+
+// signIn checks the account a player claimed for their seat. Guests get an
+// empty user id. Players who didn't pick a name get their account's.
+func signIn(args HandlerFuncArgs, account messages.Account, name string) (string, string, error) {
+	if account.UserID == "" {
+		return "", name, nil
+	}
+	if args.Users == nil {
+		return "", "", fmt.Errorf("user %s can't be loaded: %w", account.UserID, database.ErrUnavailable)
+	}
+
+	user, err := args.Users.Authenticate(args.Context, account.UserID, account.UserToken)
+	if err != nil {
+		return "", "", err
+	}
+	if name == "" {
+		name = user.DisplayName
 	}
+	return user.ID, name, nil
+}
 
-	go args.GameService.AddToGame(args.Client, value, done)
+func JoinGameHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
+	payload := args.Payload.(*messages.JoinGamePayload)
 
-	added := <- done
+	value := payload.Room
 
-	if !added {
-		return responses.SocketResponse{
-			Status: responses.Error,
-			Message: fmt.Sprintf("Could not join game %s", value),
-		}, nil
+	name, invalid := optionalName(args, payload.Name)
+	if invalid != nil {
+		return *invalid, nil
+	}
+
+	userID, name, err := signIn(args, payload.Account, name)
+	if err != nil {
+		return joinRejected(value, err), nil
+	}
+
+	client, err := args.GameService.AddToGame(args.Context, args.Client, value, name, userID)
+	if err != nil {
+		return joinRejected(value, err), nil
 	}
 
 	response := responses.SocketResponse{
 		Status: responses.Success,
-		Message: fmt.Sprintf("Joined game %s", value),
+		Message: fmt.Sprintf("Joined game %s", client.Room),
+		Content: seatContent(client),
 	}
 	return response, nil
 }
 
-func LeaveGameHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	done := make(chan bool)
-
-	value, exists := args.Message.Content["room"]
-	if !exists {
-		return responses.SocketResponse{
-			Status: responses.InvalidMessage,
-			Message: "Invalid message. Missing room field.",
-		}, nil
+// joinRejected tells the player why they couldn't join the game room.
+func joinRejected(room string, err error) responses.SocketResponse {
+	response := serviceError(err, fmt.Sprintf("Could not join game %s: %s", room, reason(err)))
+	response.Content = map[string]interface{}{
+		"room": room,
+		"reason": services.JoinRejectionFor(err),
 	}
+	return response
+}
 
-	go args.GameService.RemoveFromGame(args.Client, value, done)
+func LeaveGameHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
+	payload := args.Payload.(*messages.RoomPayload)
 
-	removed := <- done
+	value := payload.Room
 
-	if !removed {
-		return responses.SocketResponse{
-			Status: responses.Error,
-			Message: fmt.Sprintf("Could not leave game %s", value),
-		}, nil
+	if err := args.GameService.RemoveFromGame(args.Context, args.Client, value); err != nil {
+		return serviceError(err, fmt.Sprintf("Could not leave game %s: %s", value, reason(err))), nil
 	}
 
 	response := responses.SocketResponse{
@@ -202,20 +576,60 @@ func LeaveGameHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
 }
 
 func CreateGameHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
-	done := make(chan *games.Game)
+	payload := args.Payload.(*messages.CreateGamePayload)
 
-	go args.GameService.NewGame(args.Client, done)
+	name, invalid := optionalName(args, payload.Name)
+	if invalid != nil {
+		return *invalid, nil
+	}
 
-	createdGame := <- done
+	userID, name, err := signIn(args, payload.Account, name)
+	if err != nil {
+		return serviceError(err, fmt.Sprintf("Could not create game: %s", reason(err))), nil
+	}
+
+	client, err := args.GameService.NewGame(args.Context, args.Client, name, userID)
+	if err != nil {
+		return serviceError(err, fmt.Sprintf("Could not create game: %s", reason(err))), nil
+	}
 
-	content := map[string]interface{}{
-        "gameID": createdGame.Room,
-    }
+	content := seatContent(client)
+	content["gameID"] = client.Room
 
 	response := responses.SocketResponse{
 		Status: responses.Success,
-		Message: fmt.Sprintf("Created game %s", createdGame.Room),
+		Message: fmt.Sprintf("Created game %s", client.Room),
 		Content: content,
 	}
 	return response, nil
+}
+
+func ResumeSessionHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
+	payload := args.Payload.(*messages.ResumeSessionPayload)
+
+	room := payload.Room
+	token := payload.ResumeToken
+
+	state, err := args.GameService.ResumeSession(args.Context, args.Client, room, token)
+	if err != nil {
+		return serviceError(err, fmt.Sprintf("Could not resume session in game %s: %s", room, reason(err))), nil
+	}
+
+	response := responses.SocketResponse{
+		Status: responses.Success,
+		Message: fmt.Sprintf("Resumed session in game %s", room),
+		Content: state,
+	}
+	return response, nil
+}
+
+// seatContent is what a player needs to know about their own seat, including
+// the token to resume it from another connection.
+func seatContent(client *games.GameClient) map[string]interface{} {
+	return map[string]interface{}{
+		"room": client.Room,
+		"userID": client.UserID,
+		"name": client.Name,
+		"resumeToken": client.ResumeToken,
+	}
 }
\ No newline at end of file
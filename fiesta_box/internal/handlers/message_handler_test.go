@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"fiesta_box/internal/database"
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/messages"
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/services"
+	"fiesta_box/internal/sockets"
+)
+
+// fakeGameService stands in for the game service. Calling a method the test
+// did not set up panics through the nil embedded interface.
+type fakeGameService struct {
+	services.GameServiceInterface
+
+	addToGame      func(room string, name string, userID string) (*games.GameClient, error)
+	startGame      func(room string) error
+	useSavedPrompt func(room string, saved *prompts.SavedPrompt) (*prompts.Prompt, error)
+}
+
+func (f *fakeGameService) AddToGame(ctx context.Context, c *sockets.Client, room string, name string, userID string) (*games.GameClient, error) {
+	return f.addToGame(room, name, userID)
+}
+
+func (f *fakeGameService) StartGame(ctx context.Context, c *sockets.Client, room string) error {
+	return f.startGame(room)
+}
+
+func (f *fakeGameService) UseSavedPrompt(ctx context.Context, c *sockets.Client, room string, saved *prompts.SavedPrompt) (*prompts.Prompt, error) {
+	return f.useSavedPrompt(room, saved)
+}
+
+// fakePromptRepository is a prompt library holding a fixed set of prompts.
+// Methods other than Get panic through the nil embedded interface.
+type fakePromptRepository struct {
+	database.PromptRepository
+
+	saved map[string]*prompts.SavedPrompt
+	err   error
+}
+
+func (f *fakePromptRepository) Get(ctx context.Context, id string) (*prompts.SavedPrompt, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	saved, ok := f.saved[id]
+	if !ok {
+		return nil, database.ErrPromptNotFound
+	}
+	copied := *saved
+	return &copied, nil
+}
+
+func testArgs(service services.GameServiceInterface, messageType messages.MessageType, payload messages.Payload) HandlerFuncArgs {
+	return HandlerFuncArgs{
+		Context:     context.Background(),
+		Message:     messages.Message{Type: messageType},
+		Payload:     payload,
+		GameService: service,
+		Client:      &sockets.Client{},
+	}
+}
+
+func TestHandleMessageReportsInvalidFields(t *testing.T) {
+	RegisterHandler(messages.MessageTypeConfigurePromptCount, ConfigurePromptHandler)
+	RegisterPayload(messages.MessageTypeConfigurePromptCount, messages.DecoderFor[messages.PromptCountPayload]())
+
+	args := testArgs(&fakeGameService{}, messages.MessageTypeConfigurePromptCount, nil)
+	args.Message.RequestID = "req-1"
+	args.Message.Content = json.RawMessage(`{"count": 99}`)
+
+	response, err := HandleMessage(args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Status != responses.InvalidMessage {
+		t.Fatalf("expected status %d, got %d", responses.InvalidMessage, response.Status)
+	}
+
+	if response.Kind != responses.KindReply || response.RequestID != "req-1" {
+		t.Fatalf("expected a reply to req-1, got kind %q for %q", response.Kind, response.RequestID)
+	}
+	if response.Error == nil || response.Error.Code != responses.CodeInvalidPayload {
+		t.Fatalf("expected error code %s, got %+v", responses.CodeInvalidPayload, response.Error)
+	}
+	invalid, ok := response.Error.Details.(*messages.ValidationError)
+	if !ok {
+		t.Fatalf("expected validation error details, got %T", response.Error.Details)
+	}
+	if invalid.Type != messages.MessageTypeConfigurePromptCount {
+		t.Fatalf("expected type %s, got %s", messages.MessageTypeConfigurePromptCount, invalid.Type)
+	}
+	fields := []string{}
+	for _, field := range invalid.Fields {
+		fields = append(fields, field.Field)
+	}
+	if fmt.Sprint(fields) != "[room count]" {
+		t.Fatalf("expected room and count to be reported, got %v", invalid.Fields)
+	}
+}
+
+func TestStartGameHandlerReportsServiceError(t *testing.T) {
+	service := &fakeGameService{
+		startGame: func(room string) error {
+			return fmt.Errorf("failed to start game %s: %w", room, services.ErrNotMaster)
+		},
+	}
+
+	response, _ := StartGameHandler(testArgs(service, messages.MessageTypeStartGame, &messages.RoomPayload{Room: "ROOM"}))
+
+	if response.Status != responses.InvalidMessage {
+		t.Fatalf("expected status %d, got %d", responses.InvalidMessage, response.Status)
+	}
+	expected := "Could not start game ROOM: " + services.ErrNotMaster.Error()
+	if response.Message != expected {
+		t.Fatalf("expected message %q, got %q", expected, response.Message)
+	}
+	if response.Error == nil || response.Error.Code != responses.CodeNotMaster {
+		t.Fatalf("expected error code %s, got %+v", responses.CodeNotMaster, response.Error)
+	}
+}
+
+func TestStartGameHandlerReportsUnwrappedError(t *testing.T) {
+	service := &fakeGameService{
+		startGame: func(room string) error {
+			return services.ErrNotMaster
+		},
+	}
+
+	response, _ := StartGameHandler(testArgs(service, messages.MessageTypeStartGame, &messages.RoomPayload{Room: "ROOM"}))
+
+	expected := "Could not start game ROOM: " + services.ErrNotMaster.Error()
+	if response.Message != expected {
+		t.Fatalf("expected message %q, got %q", expected, response.Message)
+	}
+}
+
+func TestHandleMessageRepliesToUnknownTypes(t *testing.T) {
+	args := testArgs(&fakeGameService{}, messages.MessageType("dance_off"), nil)
+	args.Message.RequestID = "req-2"
+
+	response, _ := HandleMessage(args)
+
+	if response.RequestID != "req-2" {
+		t.Fatalf("expected the reply to echo req-2, got %q", response.RequestID)
+	}
+	if response.Error == nil || response.Error.Code != responses.CodeUnknownMessageType {
+		t.Fatalf("expected error code %s, got %+v", responses.CodeUnknownMessageType, response.Error)
+	}
+}
+
+func TestJoinGameHandlerReportsRejectionReason(t *testing.T) {
+	service := &fakeGameService{
+		addToGame: func(room string, name string, userID string) (*games.GameClient, error) {
+			return nil, fmt.Errorf("failed to join game %s: %w", room, services.ErrRoomFull)
+		},
+	}
+
+	response, _ := JoinGameHandler(testArgs(service, messages.MessageTypeJoinGame, &messages.JoinGamePayload{Room: "ROOM"}))
+
+	content, ok := response.Content.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected map content, got %T", response.Content)
+	}
+	if content["reason"] != services.JoinRoomFull {
+		t.Fatalf("expected reason %s, got %v", services.JoinRoomFull, content["reason"])
+	}
+}
+
+func TestJoinGameHandlerReturnsSeat(t *testing.T) {
+	service := &fakeGameService{
+		addToGame: func(room string, name string, userID string) (*games.GameClient, error) {
+			return &games.GameClient{Room: "ROOM", UserID: "ann", Name: name, ResumeToken: "token"}, nil
+		},
+	}
+
+	response, _ := JoinGameHandler(testArgs(service, messages.MessageTypeJoinGame, &messages.JoinGamePayload{Room: "room", Name: " Ann "}))
+
+	if response.Status != responses.Success {
+		t.Fatalf("expected status %d, got %d: %s", responses.Success, response.Status, response.Message)
+	}
+	content := response.Content.(map[string]interface{})
+	if content["room"] != "ROOM" || content["name"] != "Ann" || content["resumeToken"] != "token" {
+		t.Fatalf("unexpected seat content %v", content)
+	}
+}
+
+func TestJoinGameHandlerSignsInUser(t *testing.T) {
+	users := services.NewUserService(database.New(database.Config{Driver: database.DriverMemory}).Users())
+	user, token, err := users.CreateUser(context.Background(), "Ana", "")
+	if err != nil {
+		t.Fatalf("could not create user: %v", err)
+	}
+
+	service := &fakeGameService{
+		addToGame: func(room string, name string, userID string) (*games.GameClient, error) {
+			return &games.GameClient{Room: "ROOM", UserID: userID, Name: name}, nil
+		},
+	}
+	join := func(account messages.Account) responses.SocketResponse {
+		args := testArgs(service, messages.MessageTypeJoinGame, &messages.JoinGamePayload{Room: "room", Account: account})
+		args.Users = users
+		response, _ := JoinGameHandler(args)
+		return response
+	}
+
+	response := join(messages.Account{UserID: user.ID, UserToken: token})
+	if response.Status != responses.Success {
+		t.Fatalf("expected status %d, got %d: %s", responses.Success, response.Status, response.Message)
+	}
+	content := response.Content.(map[string]interface{})
+	if content["userID"] != user.ID || content["name"] != "Ana" {
+		t.Fatalf("expected to be seated as %s under their display name, got %v", user.ID, content)
+	}
+
+	response = join(messages.Account{UserID: user.ID, UserToken: "guess"})
+	if response.Error == nil || response.Error.Code != responses.CodeInvalidUserToken {
+		t.Fatalf("expected error code %s, got %+v", responses.CodeInvalidUserToken, response.Error)
+	}
+	if reason := response.Content.(map[string]interface{})["reason"]; reason != services.JoinBadAccount {
+		t.Fatalf("expected reason %s, got %v", services.JoinBadAccount, reason)
+	}
+}
+
+func TestHandleMessageUsesHandlerForSessionProtocol(t *testing.T) {
+	messageType := messages.MessageType("test_versioned")
+	reply := func(message string) HandlerFunc {
+		return func(HandlerFuncArgs) (responses.SocketResponse, error) {
+			return responses.SocketResponse{Status: responses.Success, Message: message}, nil
+		}
+	}
+	RegisterHandler(messageType, reply("every version"))
+	RegisterVersionedHandler(3, messageType, reply("version 3"))
+	t.Cleanup(func() { delete(HandlerRegistry, messageType) })
+
+	for version, expected := range map[messages.ProtocolVersion]string{
+		1: "every version",
+		2: "every version",
+		3: "version 3",
+		4: "version 3",
+	} {
+		args := testArgs(&fakeGameService{}, messageType, nil)
+		args.Session = NewSession(version)
+
+		response, _ := HandleMessage(args)
+		if response.Message != expected {
+			t.Errorf("protocol %d: expected %q, got %q", version, expected, response.Message)
+		}
+	}
+}
+
+func TestHelloHandlerNegotiatesProtocol(t *testing.T) {
+	session := NewSession(messages.OldestProtocol())
+	args := testArgs(&fakeGameService{}, messages.MessageTypeHello, &messages.HelloPayload{
+		Versions: []messages.ProtocolVersion{messages.LatestProtocol(), messages.LatestProtocol() + 1},
+	})
+	args.Session = session
+
+	response, _ := HelloHandler(args)
+
+	if response.Status != responses.Success || session.Protocol != messages.LatestProtocol() {
+		t.Fatalf("expected protocol %d, got %d: %s", messages.LatestProtocol(), session.Protocol, response.Message)
+	}
+	if session.Closing {
+		t.Fatal("expected the session to stay open")
+	}
+}
+
+func TestHelloHandlerRejectsUnsupportedProtocol(t *testing.T) {
+	session := NewSession(messages.OldestProtocol())
+	args := testArgs(&fakeGameService{}, messages.MessageTypeHello, &messages.HelloPayload{
+		Versions: []messages.ProtocolVersion{messages.LatestProtocol() + 1},
+	})
+	args.Session = session
+
+	response, _ := HelloHandler(args)
+
+	if response.Error == nil || response.Error.Code != responses.CodeUnsupportedProtocol {
+		t.Fatalf("expected error code %s, got %+v", responses.CodeUnsupportedProtocol, response.Error)
+	}
+	if !session.Closing {
+		t.Fatal("expected the session to be closed after the reply")
+	}
+}
+
+func TestUseSavedPromptHandlerAddsPromptToGame(t *testing.T) {
+	var used *prompts.SavedPrompt
+	service := &fakeGameService{
+		useSavedPrompt: func(room string, saved *prompts.SavedPrompt) (*prompts.Prompt, error) {
+			used = saved
+			return &prompts.Prompt{ID: "game-prompt", Text: saved.Text}, nil
+		},
+	}
+	args := testArgs(service, messages.MessageTypeUseSavedPrompt, &messages.PromptPayload{Room: "ROOM", PromptID: "saved-1"})
+	args.SavedPrompts = &fakePromptRepository{saved: map[string]*prompts.SavedPrompt{
+		"saved-1": {ID: "saved-1", Text: "  Sing the chorus  "},
+	}}
+
+	response, _ := UseSavedPromptHandler(args)
+
+	if response.Status != responses.Success {
+		t.Fatalf("expected success, got %+v", response)
+	}
+	if used == nil || used.Text != "Sing the chorus" {
+		t.Fatalf("expected the trimmed saved prompt to be used, got %+v", used)
+	}
+	content := response.Content.(map[string]interface{})
+	if content["promptID"] != "game-prompt" || content["savedPromptID"] != "saved-1" {
+		t.Fatalf("expected both prompt ids in the reply, got %v", content)
+	}
+}
+
+func TestUseSavedPromptHandlerReportsMissingLibrary(t *testing.T) {
+	tests := []struct {
+		name       string
+		repository database.PromptRepository
+		code       responses.ErrorCode
+	}{
+		{"unknown prompt", &fakePromptRepository{}, responses.CodeSavedPromptNotFound},
+		{"database error", &fakePromptRepository{err: errors.New("connection refused")}, responses.CodeUnavailable},
+		{"no database", nil, responses.CodeUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := testArgs(&fakeGameService{}, messages.MessageTypeUseSavedPrompt, &messages.PromptPayload{Room: "ROOM", PromptID: "saved-1"})
+			args.SavedPrompts = tt.repository
+
+			response, _ := UseSavedPromptHandler(args)
+
+			if response.Error == nil || response.Error.Code != tt.code {
+				t.Fatalf("expected error code %s, got %+v", tt.code, response.Error)
+			}
+		})
+	}
+}
+
+func TestHandleMessageRejectsMessagesOutOfPhase(t *testing.T) {
+	RegisterHandler(messages.MessageTypeWritePrompt, WritePromptHandler)
+	RegisterPayload(messages.MessageTypeWritePrompt, messages.DecoderFor[messages.WritePromptPayload]())
+
+	service := services.NewGameService()
+	c := &sockets.Client{}
+	master, err := service.NewGame(context.Background(), c, "Ann", "")
+	if err != nil {
+		t.Fatalf("could not create game: %v", err)
+	}
+
+	args := testArgs(service, messages.MessageTypeWritePrompt, nil)
+	args.Client = c
+	args.Message.RequestID = "req-1"
+	args.Message.Content = json.RawMessage(`{"room": "` + master.Room + `", "prompt": "Sing a song"}`)
+
+	response, _ := HandleMessage(args)
+	if response.Status != responses.InvalidMessage || response.RequestID != "req-1" {
+		t.Fatalf("expected an invalid message reply to req-1, got %+v", response)
+	}
+	if response.Error == nil || response.Error.Code != responses.CodeWrongPhase {
+		t.Fatalf("expected error code %s, got %+v", responses.CodeWrongPhase, response.Error)
+	}
+}
+
+func TestJoinGameHandlerReportsServiceRejection(t *testing.T) {
+	service := services.NewGameService()
+	master, err := service.NewGame(context.Background(), &sockets.Client{}, "Ann", "")
+	if err != nil {
+		t.Fatalf("could not create game: %v", err)
+	}
+
+	response, _ := JoinGameHandler(testArgs(service, messages.MessageTypeJoinGame, &messages.JoinGamePayload{Room: master.Room, Name: "ann"}))
+
+	content, _ := response.Content.(map[string]interface{})
+	if content["reason"] != services.JoinNameTaken {
+		t.Fatalf("expected reason %s, got %v", services.JoinNameTaken, response.Content)
+	}
+	if response.Error == nil || response.Error.Code != responses.CodeNameTaken {
+		t.Fatalf("expected error code %s, got %+v", responses.CodeNameTaken, response.Error)
+	}
+}
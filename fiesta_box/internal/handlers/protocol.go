@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+
+	"fiesta_box/internal/models/messages"
+	"fiesta_box/internal/models/responses"
+)
+
+// Session is what the server remembers about one websocket connection
+// between messages.
+type Session struct {
+	// Protocol is the message protocol version the connection speaks
+	Protocol messages.ProtocolVersion
+	// Closing is set once the connection should be closed after the reply is sent
+	Closing bool
+}
+
+func NewSession(protocol messages.ProtocolVersion) *Session {
+	return &Session{Protocol: protocol}
+}
+
+// protocol is the version messages on the session are handled with. Without
+// a session the newest version is used.
+func (s *Session) protocol() messages.ProtocolVersion {
+	if s == nil {
+		return messages.LatestProtocol()
+	}
+	return s.Protocol
+}
+
+// HelloHandler switches the connection to the newest protocol version both
+// sides speak. A client that speaks none of the supported versions is told
+// which ones are and then disconnected.
+func HelloHandler(args HandlerFuncArgs) (responses.SocketResponse, error) {
+	payload := args.Payload.(*messages.HelloPayload)
+
+	version, ok := messages.NegotiateProtocol(payload.Versions)
+	if !ok {
+		if args.Session != nil {
+			args.Session.Closing = true
+		}
+		message := fmt.Sprintf("None of protocol versions %v are supported by this server", payload.Versions)
+		return responses.SocketResponse{
+			Status:  responses.InvalidMessage,
+			Message: message,
+			Error: &responses.SocketError{
+				Code:    responses.CodeUnsupportedProtocol,
+				Message: message,
+				Details: protocolContent(args.Session.protocol()),
+			},
+		}, nil
+	}
+
+	if args.Session != nil {
+		args.Session.Protocol = version
+	}
+
+	response := responses.SocketResponse{
+		Status:  responses.Success,
+		Message: fmt.Sprintf("Speaking protocol version %d", version),
+		Content: protocolContent(version),
+	}
+	return response, nil
+}
+
+// protocolContent advertises the protocol version in use and every version
+// the server supports.
+func protocolContent(version messages.ProtocolVersion) map[string]interface{} {
+	return map[string]interface{}{
+		"version":      version,
+		"supported":    messages.SupportedProtocols,
+		"subprotocols": messages.Subprotocols(),
+	}
+}
@@ -2,10 +2,11 @@ package games
 
 import (
 	"sync"
+	"time"
 
-	"github.com/gorilla/websocket"
-
+	"fiesta_box/internal/models/prompts"
 	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
 )
 
 type GameStatus string
@@ -16,23 +17,57 @@ const (
 	Completed GameStatus = "completed"
 )
 
+const (
+	MinPlayers = 2
+	MaxPlayers = 8
+)
+
 type GameClient struct {
 	Room string `json:"room"`
-	Client *websocket.Conn `json:"client"`
+	Client *sockets.Client `json:"client"`
 	UserID string `json:"userID"`
+	// Registered is set when UserID is a user account rather than a guest seat.
+	Registered bool `json:"registered"`
+	Name string `json:"name"`
 	Connected bool `json:"connected"`
+	JoinedAt time.Time `json:"joinedAt"`
+	DisconnectedAt time.Time `json:"disconnectedAt"`
+	// ResumeToken lets the player take their seat back from a new connection.
+	ResumeToken string `json:"-"`
+}
+
+// Score tallies how a player resolved the prompts they were dealt.
+type Score struct {
+	Performed int `json:"performed"`
+	Drank int `json:"drank"`
 }
 
 type Game struct {
-	Clients map[*websocket.Conn]*GameClient `json:"clients"`
-	Broadcast chan responses.SocketResponse `json:"broadcast"`
+	// ID identifies the game internally; players join with the short Room code.
+	ID string `json:"id"`
+	Clients map[*sockets.Client]*GameClient `json:"clients"`
+	Broadcast chan responses.SocketEvent `json:"broadcast"`
 	Status GameStatus `json:"started"`
+	Phase Phase `json:"phase"`
 	Mutex sync.Mutex `json:"mutex"`
 	Room string `json:"room"`
+	MasterID string `json:"masterID"`
+	Prompts prompts.Pool `json:"prompts"`
+	Scores map[string]*Score `json:"scores"`
+	// RoundLimit ends the game after that many rounds. Zero plays until the prompts run out.
+	RoundLimit int `json:"roundLimit"`
+	Results *Results `json:"results"`
+	LastActivity time.Time `json:"lastActivity"`
+	MaxPlayers int `json:"maxPlayers"`
+	// LateJoin lets players join after the game has started.
+	LateJoin bool `json:"lateJoin"`
 }
 
 type GameState struct {
+	ID string `json:"id"`
 	Clients int `json:"clients"`
+	MaxPlayers int `json:"maxPlayers"`
 	Status GameStatus `json:"status"`
+	Phase Phase `json:"phase"`
 	Room string `json:"room"`
 }
\ No newline at end of file
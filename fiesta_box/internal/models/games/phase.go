@@ -0,0 +1,102 @@
+package games
+
+import (
+	"fiesta_box/internal/models/messages"
+)
+
+// Phase is where a game is in its lifecycle. A game moves
+// lobby -> writing prompts -> dealing <-> playing -> results -> completed,
+// alternating between dealing and playing once per round.
+type Phase string
+
+const (
+	PhaseLobby          Phase = "lobby"
+	PhaseWritingPrompts Phase = "writing_prompts"
+	PhaseDealing        Phase = "dealing"
+	PhasePlaying        Phase = "playing"
+	PhaseResults        Phase = "results"
+	PhaseCompleted      Phase = "completed"
+)
+
+// transitions lists the phases each phase may move on to.
+var transitions = map[Phase][]Phase{
+	PhaseLobby:          {PhaseWritingPrompts},
+	PhaseWritingPrompts: {PhaseDealing},
+	PhaseDealing:        {PhasePlaying},
+	PhasePlaying:        {PhaseDealing, PhaseResults},
+	PhaseResults:        {PhaseCompleted},
+}
+
+// anyPhaseMessages are accepted whatever phase the game is in.
+var anyPhaseMessages = []messages.MessageType{
+	messages.MessageTypeTransferMaster,
+	messages.MessageTypeChangePlayerName,
+	messages.MessageTypeLeaveGame,
+	messages.MessageTypeResumeSession,
+}
+
+// phaseMessages lists the message types accepted in each phase, on top of anyPhaseMessages.
+var phaseMessages = map[Phase][]messages.MessageType{
+	PhaseLobby: {
+		messages.MessageTypeJoinGame,
+		messages.MessageTypeStartGame,
+		messages.MessageTypeConfigurePromptCount,
+		messages.MessageTypeConfigureRoundCount,
+	},
+	PhaseWritingPrompts: {
+		messages.MessageTypeWritePrompt,
+		messages.MessageTypeUseSavedPrompt,
+	},
+	PhaseDealing: {
+		messages.MessageTypeReceivePrompt,
+	},
+	PhasePlaying: {
+		// lets a player fetch the prompt they hold again
+		messages.MessageTypeReceivePrompt,
+		messages.MessageTypePerformPrompt,
+		messages.MessageTypeDrinkForPrompt,
+	},
+	PhaseResults: {
+		messages.MessageTypeGetResults,
+	},
+	PhaseCompleted: {
+		messages.MessageTypeGetResults,
+	},
+}
+
+// Allows reports whether a message of the given type may be handled in this phase.
+func (p Phase) Allows(messageType messages.MessageType) bool {
+	for _, t := range anyPhaseMessages {
+		if t == messageType {
+			return true
+		}
+	}
+	for _, t := range phaseMessages[p] {
+		if t == messageType {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransitionTo reports whether the game may move from this phase to next.
+func (p Phase) CanTransitionTo(next Phase) bool {
+	for _, phase := range transitions[p] {
+		if phase == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Status is the coarse game status reported for a phase.
+func (p Phase) Status() GameStatus {
+	switch p {
+	case PhaseLobby:
+		return NotStarted
+	case PhaseCompleted:
+		return Completed
+	default:
+		return Started
+	}
+}
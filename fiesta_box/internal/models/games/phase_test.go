@@ -0,0 +1,83 @@
+package games
+
+import (
+	"testing"
+
+	"fiesta_box/internal/models/messages"
+)
+
+func TestPhaseAllows(t *testing.T) {
+	for _, test := range []struct {
+		phase       Phase
+		messageType messages.MessageType
+		allowed     bool
+	}{
+		{PhaseLobby, messages.MessageTypeJoinGame, true},
+		{PhaseLobby, messages.MessageTypeStartGame, true},
+		{PhaseLobby, messages.MessageTypeWritePrompt, false},
+		{PhaseLobby, messages.MessageTypeReceivePrompt, false},
+		{PhaseWritingPrompts, messages.MessageTypeWritePrompt, true},
+		{PhaseWritingPrompts, messages.MessageTypeUseSavedPrompt, true},
+		{PhaseWritingPrompts, messages.MessageTypeJoinGame, false},
+		{PhaseWritingPrompts, messages.MessageTypeStartGame, false},
+		{PhaseDealing, messages.MessageTypeReceivePrompt, true},
+		{PhaseDealing, messages.MessageTypePerformPrompt, false},
+		{PhasePlaying, messages.MessageTypeReceivePrompt, true},
+		{PhasePlaying, messages.MessageTypePerformPrompt, true},
+		{PhasePlaying, messages.MessageTypeDrinkForPrompt, true},
+		{PhasePlaying, messages.MessageTypeWritePrompt, false},
+		{PhaseResults, messages.MessageTypeGetResults, true},
+		{PhaseCompleted, messages.MessageTypeGetResults, true},
+		{PhaseCompleted, messages.MessageTypePerformPrompt, false},
+		// accepted whatever the phase
+		{PhaseLobby, messages.MessageTypeLeaveGame, true},
+		{PhasePlaying, messages.MessageTypeTransferMaster, true},
+		{PhaseCompleted, messages.MessageTypeChangePlayerName, true},
+		{PhaseDealing, messages.MessageTypeResumeSession, true},
+	} {
+		if allowed := test.phase.Allows(test.messageType); allowed != test.allowed {
+			t.Errorf("%s in the %s phase: expected allowed=%v, got %v", test.messageType, test.phase, test.allowed, allowed)
+		}
+	}
+}
+
+func TestPhaseCanTransitionTo(t *testing.T) {
+	for _, test := range []struct {
+		from    Phase
+		to      Phase
+		allowed bool
+	}{
+		{PhaseLobby, PhaseWritingPrompts, true},
+		{PhaseWritingPrompts, PhaseDealing, true},
+		{PhaseDealing, PhasePlaying, true},
+		{PhasePlaying, PhaseDealing, true},
+		{PhasePlaying, PhaseResults, true},
+		{PhaseResults, PhaseCompleted, true},
+		{PhaseLobby, PhaseDealing, false},
+		{PhaseLobby, PhaseCompleted, false},
+		{PhaseWritingPrompts, PhaseLobby, false},
+		{PhaseDealing, PhaseResults, false},
+		{PhasePlaying, PhaseCompleted, false},
+		{PhaseCompleted, PhaseLobby, false},
+		{PhaseCompleted, PhaseCompleted, false},
+	} {
+		if allowed := test.from.CanTransitionTo(test.to); allowed != test.allowed {
+			t.Errorf("%s -> %s: expected allowed=%v, got %v", test.from, test.to, test.allowed, allowed)
+		}
+	}
+}
+
+func TestPhaseStatus(t *testing.T) {
+	for phase, status := range map[Phase]GameStatus{
+		PhaseLobby:          NotStarted,
+		PhaseWritingPrompts: Started,
+		PhaseDealing:        Started,
+		PhasePlaying:        Started,
+		PhaseResults:        Started,
+		PhaseCompleted:      Completed,
+	} {
+		if got := phase.Status(); got != status {
+			t.Errorf("%s: expected status %v, got %v", phase, status, got)
+		}
+	}
+}
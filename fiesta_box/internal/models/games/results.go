@@ -0,0 +1,33 @@
+package games
+
+import (
+	"time"
+)
+
+type PlayerResult struct {
+	UserID     string `json:"userID"`
+	Registered bool   `json:"registered"`
+	Name       string `json:"name"`
+	Performed  int    `json:"performed"`
+	Drank      int    `json:"drank"`
+}
+
+// AuthorResult is the player whose prompts were drunk for instead of
+// performed the most times. Each prompt is only dealt once, so skips are
+// counted per author rather than per prompt.
+type AuthorResult struct {
+	AuthorID string `json:"authorID"`
+	Name     string `json:"name"`
+	Skipped  int    `json:"skipped"`
+}
+
+// Results summarises a finished game for the recap screen.
+type Results struct {
+	GameID            string         `json:"gameID"`
+	Room              string         `json:"room"`
+	Rounds            int            `json:"rounds"`
+	Players           []PlayerResult `json:"players"`
+	MostSkippedAuthor *AuthorResult  `json:"mostSkippedAuthor"`
+	MVP               *PlayerResult  `json:"mvp"`
+	CompletedAt       time.Time      `json:"completedAt"`
+}
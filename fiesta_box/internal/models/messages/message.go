@@ -1,5 +1,7 @@
 package messages
 
+import "encoding/json"
+
 type MessageType string
 
 const (
@@ -15,9 +17,16 @@ const (
 	MessageTypeJoinGame MessageType 			= "join_game"
 	MessageTypeLeaveGame MessageType 			= "leave_game"
 	MessageTypeCreateGame MessageType 			= "create_game"
+	MessageTypeConfigureRoundCount MessageType 	= "configure_round_count"
+	MessageTypeGetResults MessageType 			= "get_results"
+	MessageTypeResumeSession MessageType 		= "resume_session"
+	MessageTypeHello MessageType 				= "hello"
 )
 
 type Message struct {
 	Type MessageType `json:"type"`
-	Content map[string]string `json:"content"`
+	// RequestID is chosen by the client and echoed back in the reply
+	RequestID string `json:"requestId,omitempty"`
+	// Content is decoded into the payload registered for Type
+	Content json.RawMessage `json:"content"`
 }
\ No newline at end of file
@@ -0,0 +1,132 @@
+package messages
+
+import "fiesta_box/internal/models/prompts"
+
+// RoomPayload is the content of messages that only need to name the game
+// room: start_game, receive_prompt, leave_game and get_results.
+type RoomPayload struct {
+	Room string `json:"room"`
+}
+
+func (p *RoomPayload) Validate() []FieldError {
+	return requireString(nil, "room", p.Room)
+}
+
+// Account claims a user account for a seat so the player's results and saved
+// prompts follow them between games. Leaving it out seats a guest.
+type Account struct {
+	UserID    string `json:"userID,omitempty"`
+	UserToken string `json:"userToken,omitempty"`
+}
+
+// validate requires the token along with the user and the other way around.
+func (a Account) validate(fields []FieldError) []FieldError {
+	if a.UserID == "" && a.UserToken == "" {
+		return fields
+	}
+	fields = requireString(fields, "userID", a.UserID)
+	return requireString(fields, "userToken", a.UserToken)
+}
+
+// CreateGamePayload is the content of create_game. Leaving the name out
+// gets the player a default one, or their account's display name.
+type CreateGamePayload struct {
+	Name string `json:"name,omitempty"`
+	Account
+}
+
+func (p *CreateGamePayload) Validate() []FieldError {
+	return p.Account.validate(nil)
+}
+
+// JoinGamePayload is the content of join_game. Leaving the name out gets
+// the player a default one, or their account's display name.
+type JoinGamePayload struct {
+	Room string `json:"room"`
+	Name string `json:"name,omitempty"`
+	Account
+}
+
+func (p *JoinGamePayload) Validate() []FieldError {
+	fields := requireString(nil, "room", p.Room)
+	return p.Account.validate(fields)
+}
+
+// ChangePlayerNamePayload is the content of change_player_name.
+type ChangePlayerNamePayload struct {
+	Room string `json:"room"`
+	Name string `json:"name"`
+}
+
+func (p *ChangePlayerNamePayload) Validate() []FieldError {
+	fields := requireString(nil, "room", p.Room)
+	return requireString(fields, "name", p.Name)
+}
+
+// TransferMasterPayload is the content of transfer_master.
+type TransferMasterPayload struct {
+	Room   string `json:"room"`
+	UserID string `json:"userID"`
+}
+
+func (p *TransferMasterPayload) Validate() []FieldError {
+	fields := requireString(nil, "room", p.Room)
+	return requireString(fields, "userID", p.UserID)
+}
+
+// PromptCountPayload is the content of configure_prompt_count.
+type PromptCountPayload struct {
+	Room  string `json:"room"`
+	Count *int   `json:"count"`
+}
+
+func (p *PromptCountPayload) Validate() []FieldError {
+	fields := requireString(nil, "room", p.Room)
+	return requireRange(fields, "count", p.Count, prompts.MinPerPlayer, prompts.MaxPerPlayer)
+}
+
+// RoundCountPayload is the content of configure_round_count. Zero rounds
+// plays until the prompts run out.
+type RoundCountPayload struct {
+	Room   string `json:"room"`
+	Rounds *int   `json:"rounds"`
+}
+
+func (p *RoundCountPayload) Validate() []FieldError {
+	fields := requireString(nil, "room", p.Room)
+	return requireRange(fields, "rounds", p.Rounds, 0, prompts.MaxPerPlayer)
+}
+
+// WritePromptPayload is the content of write_prompt.
+type WritePromptPayload struct {
+	Room   string `json:"room"`
+	Prompt string `json:"prompt"`
+}
+
+func (p *WritePromptPayload) Validate() []FieldError {
+	fields := requireString(nil, "room", p.Room)
+	return requireString(fields, "prompt", p.Prompt)
+}
+
+// PromptPayload is the content of messages about one prompt:
+// use_saved_prompt, perform_prompt and drink_for_prompt.
+type PromptPayload struct {
+	Room     string `json:"room"`
+	PromptID string `json:"promptID"`
+}
+
+func (p *PromptPayload) Validate() []FieldError {
+	fields := requireString(nil, "room", p.Room)
+	return requireString(fields, "promptID", p.PromptID)
+}
+
+// ResumeSessionPayload is the content of resume_session.
+type ResumeSessionPayload struct {
+	Room        string `json:"room"`
+	ResumeToken string `json:"resumeToken"`
+}
+
+func (p *ResumeSessionPayload) Validate() []FieldError {
+	fields := requireString(nil, "room", p.Room)
+	return requireString(fields, "resumeToken", p.ResumeToken)
+}
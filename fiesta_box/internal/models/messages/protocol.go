@@ -0,0 +1,96 @@
+package messages
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersion is a version of the websocket message protocol. Clients
+// pick one with the Sec-WebSocket-Protocol header or a hello message.
+type ProtocolVersion int
+
+const (
+	ProtocolV1 ProtocolVersion = 1
+)
+
+// SupportedProtocols lists the versions this server speaks, newest first.
+var SupportedProtocols = []ProtocolVersion{ProtocolV1}
+
+// subprotocolPrefix is prepended to the version number to make the
+// Sec-WebSocket-Protocol name, e.g. "fiesta-box.v1".
+const subprotocolPrefix = "fiesta-box.v"
+
+// LatestProtocol is the newest version the server speaks.
+func LatestProtocol() ProtocolVersion {
+	return SupportedProtocols[0]
+}
+
+// OldestProtocol is the oldest version the server still speaks. Clients that
+// don't say which version they want are assumed to speak it.
+func OldestProtocol() ProtocolVersion {
+	return SupportedProtocols[len(SupportedProtocols)-1]
+}
+
+func (v ProtocolVersion) Subprotocol() string {
+	return fmt.Sprintf("%s%d", subprotocolPrefix, v)
+}
+
+// Supported reports whether the server still speaks the version.
+func (v ProtocolVersion) Supported() bool {
+	for _, supported := range SupportedProtocols {
+		if v == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSubprotocol reads the version out of a Sec-WebSocket-Protocol name.
+func ParseSubprotocol(name string) (ProtocolVersion, bool) {
+	number, ok := strings.CutPrefix(name, subprotocolPrefix)
+	if !ok {
+		return 0, false
+	}
+	version, err := strconv.Atoi(number)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return ProtocolVersion(version), true
+}
+
+// Subprotocols lists the Sec-WebSocket-Protocol names of every supported
+// version, newest first.
+func Subprotocols() []string {
+	names := make([]string, len(SupportedProtocols))
+	for i, version := range SupportedProtocols {
+		names[i] = version.Subprotocol()
+	}
+	return names
+}
+
+// NegotiateProtocol picks the newest version that both the client and the
+// server speak.
+func NegotiateProtocol(offered []ProtocolVersion) (ProtocolVersion, bool) {
+	for _, supported := range SupportedProtocols {
+		for _, version := range offered {
+			if version == supported {
+				return version, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// HelloPayload is the content of hello, which lists every version the client
+// speaks.
+type HelloPayload struct {
+	Versions []ProtocolVersion `json:"versions"`
+}
+
+func (p *HelloPayload) Validate() []FieldError {
+	if len(p.Versions) == 0 {
+		return []FieldError{{Field: "versions", Problem: "versions must list at least one protocol version"}}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package messages
+
+import "testing"
+
+func TestParseSubprotocol(t *testing.T) {
+	for name, expected := range map[string]ProtocolVersion{
+		ProtocolV1.Subprotocol(): ProtocolV1,
+		"fiesta-box.v12":         12,
+		"fiesta-box.v0":          0,
+		"fiesta-box.vx":          0,
+		"graphql-ws":             0,
+	} {
+		version, ok := ParseSubprotocol(name)
+		if ok != (expected != 0) || version != expected {
+			t.Errorf("%s: expected %d, got %d (ok=%v)", name, expected, version, ok)
+		}
+	}
+}
+
+func TestNegotiateProtocolPrefersNewest(t *testing.T) {
+	version, ok := NegotiateProtocol([]ProtocolVersion{OldestProtocol(), LatestProtocol(), LatestProtocol() + 1})
+	if !ok || version != LatestProtocol() {
+		t.Fatalf("expected %d, got %d (ok=%v)", LatestProtocol(), version, ok)
+	}
+
+	if _, ok := NegotiateProtocol([]ProtocolVersion{LatestProtocol() + 1}); ok {
+		t.Fatal("expected no version in common")
+	}
+}
@@ -0,0 +1,129 @@
+package messages
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FieldError describes what is wrong with one field of a message's content.
+type FieldError struct {
+	Field   string `json:"field"`
+	Problem string `json:"problem"`
+}
+
+// ValidationError lists every problem found in a message's content, so a
+// client can fix them all at once instead of one vague error at a time.
+type ValidationError struct {
+	Type   MessageType  `json:"type"`
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		problems[i] = field.Problem
+	}
+	return fmt.Sprintf("invalid %s message: %s", e.Type, strings.Join(problems, "; "))
+}
+
+// Payload is the typed content of one type of message.
+type Payload interface {
+	// Validate reports every field that is missing or out of range.
+	Validate() []FieldError
+}
+
+// PayloadDecoder turns the raw content of a message into its typed payload.
+// Content that can't be decoded or fails validation is reported as a
+// *ValidationError.
+type PayloadDecoder func(content json.RawMessage) (Payload, error)
+
+// DecoderFor builds the PayloadDecoder for the payload type T.
+func DecoderFor[T any, P interface {
+	*T
+	Payload
+}]() PayloadDecoder {
+	return func(content json.RawMessage) (Payload, error) {
+		payload := P(new(T))
+		if field := decodeContent(content, payload); field != nil {
+			return nil, &ValidationError{Fields: []FieldError{*field}}
+		}
+		if fields := payload.Validate(); len(fields) > 0 {
+			return nil, &ValidationError{Fields: fields}
+		}
+		return payload, nil
+	}
+}
+
+// decodeContent strictly decodes content into v. Missing content decodes as
+// an empty object so that Validate can report the required fields.
+func decodeContent(content json.RawMessage, v interface{}) *FieldError {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 || bytes.Equal(content, []byte("null")) {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return &FieldError{
+			Field:   typeErr.Field,
+			Problem: fmt.Sprintf("%s must be %s", typeErr.Field, describeKind(typeErr.Type)),
+		}
+	case errors.As(err, &typeErr):
+		return &FieldError{Field: "content", Problem: "content must be an object"}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &FieldError{Field: field, Problem: fmt.Sprintf("%s is not a known field", field)}
+	default:
+		return &FieldError{Field: "content", Problem: "content is not valid JSON"}
+	}
+}
+
+func describeKind(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	default:
+		return "an object"
+	}
+}
+
+// requireString reports the field if it is missing or blank.
+func requireString(fields []FieldError, field string, value string) []FieldError {
+	if strings.TrimSpace(value) == "" {
+		fields = append(fields, FieldError{Field: field, Problem: fmt.Sprintf("%s is required", field)})
+	}
+	return fields
+}
+
+// requireRange reports the field if it is missing or outside min..max.
+func requireRange(fields []FieldError, field string, value *int, min int, max int) []FieldError {
+	switch {
+	case value == nil:
+		fields = append(fields, FieldError{Field: field, Problem: fmt.Sprintf("%s is required", field)})
+	case *value < min || *value > max:
+		fields = append(fields, FieldError{Field: field, Problem: fmt.Sprintf("%s must be between %d and %d", field, min, max)})
+	}
+	return fields
+}
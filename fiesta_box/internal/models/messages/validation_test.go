@@ -0,0 +1,57 @@
+package messages
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func decodeError(t *testing.T, decoder PayloadDecoder, content string) *ValidationError {
+	t.Helper()
+
+	_, err := decoder(json.RawMessage(content))
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a validation error for %s, got %v", content, err)
+	}
+	return invalid
+}
+
+func TestDecoderReportsWrongTypes(t *testing.T) {
+	invalid := decodeError(t, DecoderFor[PromptCountPayload](), `{"room": "ABCD", "count": "3"}`)
+
+	if len(invalid.Fields) != 1 || invalid.Fields[0].Field != "count" {
+		t.Fatalf("expected count to be reported, got %v", invalid.Fields)
+	}
+	if invalid.Fields[0].Problem != "count must be a whole number" {
+		t.Fatalf("unexpected problem %q", invalid.Fields[0].Problem)
+	}
+}
+
+func TestDecoderReportsUnknownFields(t *testing.T) {
+	invalid := decodeError(t, DecoderFor[RoomPayload](), `{"room": "ABCD", "rooom": "ABCD"}`)
+
+	if len(invalid.Fields) != 1 || invalid.Fields[0].Field != "rooom" {
+		t.Fatalf("expected rooom to be reported, got %v", invalid.Fields)
+	}
+}
+
+func TestDecoderReportsEveryMissingField(t *testing.T) {
+	invalid := decodeError(t, DecoderFor[ResumeSessionPayload](), ``)
+
+	if len(invalid.Fields) != 2 {
+		t.Fatalf("expected room and resumeToken to be reported, got %v", invalid.Fields)
+	}
+}
+
+func TestDecoderAcceptsValidContent(t *testing.T) {
+	payload, err := DecoderFor[RoundCountPayload]()(json.RawMessage(`{"room": "ABCD", "rounds": 0}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rounds := payload.(*RoundCountPayload)
+	if rounds.Room != "ABCD" || *rounds.Rounds != 0 {
+		t.Fatalf("unexpected payload %+v", rounds)
+	}
+}
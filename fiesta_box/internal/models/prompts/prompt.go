@@ -0,0 +1,89 @@
+package prompts
+
+import (
+	"time"
+)
+
+const (
+	DefaultPerPlayer = 3
+	MinPerPlayer     = 1
+	MaxPerPlayer     = 10
+	MaxTextLength    = 200
+)
+
+type Resolution string
+
+const (
+	Performed Resolution = "performed"
+	Drank     Resolution = "drank"
+)
+
+type Prompt struct {
+	ID        string    `json:"id"`
+	Text      string    `json:"text"`
+	AuthorID  string    `json:"authorID"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Seq is the prompt's place in the order its pool was written in.
+	Seq int `json:"seq"`
+}
+
+// Outcome records how a player resolved a prompt they were dealt.
+type Outcome struct {
+	PromptID   string     `json:"promptID"`
+	PlayerID   string     `json:"playerID"`
+	Resolution Resolution `json:"resolution"`
+	Round      int        `json:"round"`
+	ResolvedAt time.Time  `json:"resolvedAt"`
+}
+
+type Pool struct {
+	Prompts   map[string]*Prompt `json:"prompts"`
+	PerPlayer int                `json:"perPlayer"`
+	Ready     bool               `json:"ready"`
+
+	// Round is the current dealing round, starting at 1. Every player gets at
+	// most one prompt per round.
+	Round int `json:"round"`
+	// DealtTo maps each dealt prompt id to the player it was dealt to.
+	DealtTo map[string]string `json:"dealtTo"`
+	// Held maps a player to the prompt id they currently hold.
+	Held map[string]string `json:"held"`
+	// LastRound maps a player to the last round they were dealt in.
+	LastRound map[string]int `json:"lastRound"`
+	// Outcomes lists every resolved prompt in the order it was resolved.
+	Outcomes []Outcome `json:"outcomes"`
+}
+
+func NewPool() Pool {
+	return Pool{
+		Prompts:   map[string]*Prompt{},
+		PerPlayer: DefaultPerPlayer,
+		Round:     1,
+		DealtTo:   map[string]string{},
+		Held:      map[string]string{},
+		LastRound: map[string]int{},
+	}
+}
+
+// Add puts the prompt in the pool after every prompt already in it.
+// Prompts are never taken out of a pool, so its size is the next Seq.
+func (p *Pool) Add(prompt *Prompt) {
+	prompt.Seq = len(p.Prompts)
+	p.Prompts[prompt.ID] = prompt
+}
+
+// Undealt returns how many prompts have not been dealt yet.
+func (p *Pool) Undealt() int {
+	return len(p.Prompts) - len(p.DealtTo)
+}
+
+// CountBy returns how many prompts in the pool were written by the author.
+func (p *Pool) CountBy(authorID string) int {
+	count := 0
+	for _, prompt := range p.Prompts {
+		if prompt.AuthorID == authorID {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,72 @@
+package prompts
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	MaxTags           = 10
+	MaxTagLength      = 30
+	MaxCategoryLength = 50
+	DefaultPageSize   = 20
+	MaxPageSize       = 100
+)
+
+// SavedPrompt is a prompt kept in the prompt library so hosts can reuse it
+// across games. AuthorID is set when the author saved it with a user account.
+type SavedPrompt struct {
+	ID        string    `json:"id"`
+	Text      string    `json:"text"`
+	Category  string    `json:"category"`
+	Tags      []string  `json:"tags"`
+	Author    string    `json:"author"`
+	AuthorID  string    `json:"authorID,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SavedPromptQuery narrows down a search of the prompt library. Empty fields
+// match everything.
+type SavedPromptQuery struct {
+	// Text matches prompts containing it, ignoring case.
+	Text     string
+	Category string
+	// Tag matches prompts carrying the tag.
+	Tag      string
+	Author   string
+	AuthorID string
+	Limit    int
+	Offset   int
+}
+
+// Page clamps the limit and offset to what the library will return.
+func (q SavedPromptQuery) Page() (limit int, offset int) {
+	limit, offset = q.Limit, q.Offset
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// NormalizeTags lower-cases, trims and de-duplicates tags, dropping blank ones.
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
@@ -0,0 +1,43 @@
+package responses
+
+// ErrorCode is a machine-readable reason a message failed, finer grained than
+// its StatusCode so clients can react without parsing the message.
+type ErrorCode string
+
+const (
+	CodeUnknownMessageType  ErrorCode = "unknown_message_type"
+	CodeInvalidPayload      ErrorCode = "invalid_payload"
+	CodeRoomNotFound        ErrorCode = "room_not_found"
+	CodeRoomFull            ErrorCode = "room_full"
+	CodeGameStarted         ErrorCode = "game_started"
+	CodeAlreadyJoined       ErrorCode = "already_joined"
+	CodeNotInGame           ErrorCode = "not_in_game"
+	CodeNotMaster           ErrorCode = "not_master"
+	CodeWrongPhase          ErrorCode = "wrong_phase"
+	CodeNotEnoughPlayers    ErrorCode = "not_enough_players"
+	CodeTooManyPlayers      ErrorCode = "too_many_players"
+	CodePlayerNotFound      ErrorCode = "player_not_found"
+	CodePlayerDisconnected  ErrorCode = "player_disconnected"
+	CodeNameTaken           ErrorCode = "name_taken"
+	CodePromptQuotaReached  ErrorCode = "prompt_quota_reached"
+	CodePromptNotHeld       ErrorCode = "prompt_not_held"
+	CodeWaitForRound        ErrorCode = "wait_for_round"
+	CodeNoPromptsLeft       ErrorCode = "no_prompts_left"
+	CodeInvalidResumeToken  ErrorCode = "invalid_resume_token"
+	CodeNoRoomCodes         ErrorCode = "no_room_codes"
+	CodeSavedPromptNotFound ErrorCode = "saved_prompt_not_found"
+	CodeUserNotFound        ErrorCode = "user_not_found"
+	CodeInvalidUserToken    ErrorCode = "invalid_user_token"
+	CodeUnavailable         ErrorCode = "unavailable"
+	CodeUnsupportedProtocol ErrorCode = "unsupported_protocol"
+	CodeTimeout             ErrorCode = "timeout"
+	CodeInternal            ErrorCode = "internal"
+)
+
+// SocketError explains why a message failed. Details carries anything
+// specific to the code, such as the fields that failed validation.
+type SocketError struct {
+	Code    ErrorCode   `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
@@ -0,0 +1,30 @@
+package responses
+
+// EventType names something that happened in a game room.
+type EventType string
+
+const (
+	EventPlayerJoined       EventType = "player_joined"
+	EventPlayerLeft         EventType = "player_left"
+	EventPlayerRenamed      EventType = "player_renamed"
+	EventPlayerDisconnected EventType = "player_disconnected"
+	EventPlayerReconnected  EventType = "player_reconnected"
+	EventMasterChanged      EventType = "master_changed"
+	EventPhaseChanged       EventType = "phase_changed"
+	EventPromptCountChanged EventType = "prompt_count_changed"
+	EventRoundCountChanged  EventType = "round_count_changed"
+	EventPromptWritten      EventType = "prompt_written"
+	EventPromptDealt        EventType = "prompt_dealt"
+	EventPromptResolved     EventType = "prompt_resolved"
+	EventResultsReady       EventType = "results_ready"
+	EventRoomClosed         EventType = "room_closed"
+)
+
+// SocketEvent is pushed to every client in a game room when something changes
+// there. It never answers a message, so it carries no requestId or status.
+type SocketEvent struct {
+	Kind    Kind        `json:"kind"`
+	Event   EventType   `json:"event"`
+	Message string      `json:"message"`
+	Content interface{} `json:"content"`
+}
@@ -27,8 +27,22 @@ func (s StatusCode) String() string {
 	}
 }
 
+// Kind tells clients whether a payload answers one of their messages or was
+// pushed to them by the server.
+type Kind string
+
+const (
+	KindReply Kind = "reply"
+	KindEvent Kind = "event"
+)
+
+// SocketResponse is the reply to a single client message. RequestID echoes the
+// requestId the client sent with it, if any.
 type SocketResponse struct {
+	Kind Kind `json:"kind"`
+	RequestID string `json:"requestId,omitempty"`
 	Status StatusCode `json:"status"`
 	Message string `json:"message"`
 	Content interface{} `json:"content"`
+	Error *SocketError `json:"error,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,45 @@
+package users
+
+import "time"
+
+const (
+	DefaultHistoryPageSize = 20
+	MaxHistoryPageSize     = 100
+)
+
+// GameRecord is how one finished game went for a user. Name is what they were
+// called in that game and Rank their place in it, starting at 1.
+type GameRecord struct {
+	GameID      string    `json:"gameID"`
+	Room        string    `json:"room"`
+	Name        string    `json:"name"`
+	Performed   int       `json:"performed"`
+	Drank       int       `json:"drank"`
+	Rank        int       `json:"rank"`
+	Players     int       `json:"players"`
+	Rounds      int       `json:"rounds"`
+	MVP         bool      `json:"mvp"`
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+// Totals adds up every game a user has finished.
+type Totals struct {
+	Games     int `json:"games"`
+	Performed int `json:"performed"`
+	Drank     int `json:"drank"`
+	MVPs      int `json:"mvps"`
+}
+
+// HistoryPage clamps the limit and offset of a page of history.
+func HistoryPage(limit int, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultHistoryPageSize
+	}
+	if limit > MaxHistoryPageSize {
+		limit = MaxHistoryPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
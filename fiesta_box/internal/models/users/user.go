@@ -0,0 +1,58 @@
+package users
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const MaxAvatarURLLength = 2048
+
+var ErrInvalidAvatarURL = errors.New("avatar must be an http or https URL")
+
+// User is a player who keeps the same identity across games, so their results
+// and saved prompts follow them from one party to the next.
+type User struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	// AvatarURL is optional.
+	AvatarURL string    `json:"avatarURL,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// TokenHash is the SHA-256 of the secret the user proves who they are
+	// with. The secret itself is only ever shown to the user once.
+	TokenHash string `json:"-"`
+}
+
+// HashToken is how a user's token is kept at rest.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HasToken reports whether token is the user's secret.
+func (u *User) HasToken(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashToken(token)), []byte(u.TokenHash)) == 1
+}
+
+// NormalizeAvatarURL trims the avatar URL and checks it is one browsers can
+// load. A blank URL means the user has no avatar.
+func NormalizeAvatarURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if len(raw) > MaxAvatarURLLength {
+		return "", fmt.Errorf("avatar URL cannot be longer than %d characters: %w", MaxAvatarURLLength, ErrInvalidAvatarURL)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrInvalidAvatarURL
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"fiesta_box/internal/database"
+)
+
+// databaseMonitor checks the database in the background, so that requests can
+// tell whether persistence is available without waiting on a ping. Games are
+// kept in memory and carry on while the database is down.
+type databaseMonitor struct {
+	db          database.Service
+	autoMigrate bool
+
+	mutex    sync.RWMutex
+	health   map[string]string
+	migrated bool
+}
+
+func newDatabaseMonitor(db database.Service, autoMigrate bool) *databaseMonitor {
+	return &databaseMonitor{db: db, autoMigrate: autoMigrate}
+}
+
+// Start checks the database every interval in the background. Calling the
+// returned function stops it.
+func (m *databaseMonitor) Start(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	quit := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				m.check()
+			case <-quit:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+	}
+}
+
+// check pings the database and, the first time it answers, applies pending
+// migrations when the server is set to.
+func (m *databaseMonitor) check() {
+	wasAvailable := m.Available()
+	health := m.db.Health()
+
+	m.mutex.Lock()
+	m.health = health
+	migrate := m.autoMigrate && !m.migrated && health["status"] == "up"
+	m.mutex.Unlock()
+
+	if migrate {
+		err := migrateDatabase(m.db)
+		if err != nil {
+			log.Printf("Could not migrate the database, will retry: %v", err)
+		}
+		m.mutex.Lock()
+		m.migrated = err == nil
+		m.mutex.Unlock()
+	}
+
+	available := m.Available()
+	if wasAvailable && !available {
+		log.Print("The database is unavailable, games carry on without saved prompts")
+	} else if !wasAvailable && available {
+		log.Print("The database is available")
+	}
+}
+
+// Available reports whether the last check found the database up and its
+// schema migrated. A server without a monitor trusts its database.
+func (m *databaseMonitor) Available() bool {
+	if m == nil {
+		return true
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.health["status"] == "up" && (m.migrated || !m.autoMigrate)
+}
+
+// Health is the result of the last check.
+func (m *databaseMonitor) Health() map[string]string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.health == nil {
+		return map[string]string{"status": "unknown"}
+	}
+	return m.health
+}
+
+// readiness is the body of GET /health/ready.
+type readiness struct {
+	// Status is "ok", or "degraded" while the database is unavailable.
+	Status   string            `json:"status"`
+	Database map[string]string `json:"database"`
+	Games    int               `json:"games"`
+}
+
+// livenessHandler only reports that the server is running. It never touches
+// the database, so a database outage doesn't get the server restarted.
+func (s *Server) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readinessHandler reports whether the server can take players. It still
+// answers 200 while the database is down because games don't need it; only
+// persistence features are degraded then.
+func (s *Server) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	ready := readiness{Status: "ok", Games: s.game.ServiceHealth().Games}
+	if s.dbMonitor != nil {
+		ready.Database = s.dbMonitor.Health()
+	}
+	if !s.dbMonitor.Available() {
+		ready.Status = "degraded"
+	}
+	writeJSON(w, http.StatusOK, ready)
+}
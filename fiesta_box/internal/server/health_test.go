@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fiesta_box/internal/database"
+	"fiesta_box/internal/services"
+)
+
+// flakyDatabase is a memory store whose health can be switched off.
+type flakyDatabase struct {
+	database.Service
+	up bool
+}
+
+func (db *flakyDatabase) Health() map[string]string {
+	if !db.up {
+		return map[string]string{"status": "down", "error": "db down: connection refused"}
+	}
+	return db.Service.Health()
+}
+
+func TestServerKeepsServingWhileDatabaseIsDown(t *testing.T) {
+	db := &flakyDatabase{Service: database.New(database.Config{Driver: database.DriverMemory})}
+	s := &Server{db: db, dbMonitor: newDatabaseMonitor(db, true), game: services.NewGameService()}
+	handler := s.RegisterRoutes()
+	s.dbMonitor.check()
+
+	request := func(method string, path string, body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		decoded := map[string]interface{}{}
+		json.Unmarshal(w.Body.Bytes(), &decoded)
+		return w.Code, decoded
+	}
+
+	if code, _ := request(http.MethodGet, "/health/live", ""); code != http.StatusOK {
+		t.Fatalf("expected liveness to ignore the database, got %d", code)
+	}
+	code, ready := request(http.MethodGet, "/health/ready", "")
+	if code != http.StatusOK || ready["status"] != "degraded" {
+		t.Fatalf("expected a degraded 200, got %d %v", code, ready)
+	}
+	if code, _ := request(http.MethodGet, "/", ""); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the database health to report 503, got %d", code)
+	}
+	if code, _ := request(http.MethodPost, "/prompts", `{"text": "Sing a song"}`); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected saved prompts to be unavailable, got %d", code)
+	}
+
+	db.up = true
+	s.dbMonitor.check()
+	code, ready = request(http.MethodGet, "/health/ready", "")
+	if code != http.StatusOK || ready["status"] != "ok" {
+		t.Fatalf("expected ready once the database is back, got %d %v", code, ready)
+	}
+	if code, _ := request(http.MethodPost, "/prompts", `{"text": "Sing a song"}`); code != http.StatusCreated {
+		t.Fatalf("expected saved prompts to work again, got %d", code)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var ErrInvalidOrigin = errors.New("invalid origin pattern")
+
+// OriginPolicy decides which browser origins may call the API and open
+// websockets. Patterns are full origins such as "https://fiesta.example.com",
+// or "https://*.example.com" to allow every subdomain of example.com.
+type OriginPolicy struct {
+	// AllowAll accepts every origin. It is only meant for local development.
+	AllowAll bool
+	patterns []originPattern
+}
+
+type originPattern struct {
+	scheme string
+	// host includes the port, if any. For a wildcard it is the suffix every
+	// matching host must end with, e.g. ".example.com".
+	host     string
+	wildcard bool
+}
+
+func NewOriginPolicy(patterns []string, allowAll bool) (*OriginPolicy, error) {
+	policy := &OriginPolicy{AllowAll: allowAll}
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			continue
+		}
+		parsed, err := parseOriginPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		policy.patterns = append(policy.patterns, parsed)
+	}
+	return policy, nil
+}
+
+func parseOriginPattern(pattern string) (originPattern, error) {
+	u, err := url.Parse(strings.ToLower(strings.TrimSpace(pattern)))
+	if err != nil || u.Scheme == "" || u.Host == "" || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.User != nil {
+		return originPattern{}, fmt.Errorf("%w %q: expected scheme://host[:port]", ErrInvalidOrigin, pattern)
+	}
+
+	host := u.Host
+	if suffix, ok := strings.CutPrefix(host, "*."); ok {
+		host = "." + suffix
+		if strings.Contains(suffix, "*") || !strings.Contains(strings.Split(suffix, ":")[0], ".") {
+			return originPattern{}, fmt.Errorf("%w %q: a wildcard must cover subdomains of a domain", ErrInvalidOrigin, pattern)
+		}
+		return originPattern{scheme: u.Scheme, host: host, wildcard: true}, nil
+	}
+	if strings.Contains(host, "*") {
+		return originPattern{}, fmt.Errorf("%w %q: a wildcard is only allowed as the first label", ErrInvalidOrigin, pattern)
+	}
+	return originPattern{scheme: u.Scheme, host: host}, nil
+}
+
+func (p originPattern) matches(scheme string, host string) bool {
+	if scheme != p.scheme {
+		return false
+	}
+	if p.wildcard {
+		return len(host) > len(p.host) && strings.HasSuffix(host, p.host)
+	}
+	return host == p.host
+}
+
+// Allowed reports whether a request from the origin may be served. Requests
+// without an Origin header don't come from a browser page, and requests from
+// the server's own origin are not cross-origin, so both are always allowed.
+func (p *OriginPolicy) Allowed(r *http.Request) bool {
+	if p == nil {
+		p = &OriginPolicy{}
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || p.AllowAll {
+		return true
+	}
+
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, pattern := range p.patterns {
+		if pattern.matches(u.Scheme, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOrigin is the websocket upgrader's origin check.
+func (p *OriginPolicy) CheckOrigin(r *http.Request) bool {
+	if p.Allowed(r) {
+		return true
+	}
+	log.Printf("Rejected websocket from origin %s", r.Header.Get("Origin"))
+	return false
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginPolicyAllowlist(t *testing.T) {
+	policy, err := NewOriginPolicy([]string{"https://fiesta.example.com", "https://*.party.example.com", "http://localhost:5173"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for origin, expected := range map[string]bool{
+		"":                                    true,
+		"http://api.example.com":              true,
+		"https://fiesta.example.com":          true,
+		"https://FIESTA.example.com":          true,
+		"http://fiesta.example.com":           false,
+		"https://fiesta.example.com:8443":     false,
+		"https://a.party.example.com":         true,
+		"https://a.b.party.example.com":       true,
+		"https://party.example.com":           false,
+		"https://evilparty.example.com":       false,
+		"https://a.party.example.com.evil.io": false,
+		"http://localhost:5173":               true,
+		"http://localhost:3000":               false,
+		"null":                                false,
+	} {
+		r := httptest.NewRequest("GET", "http://api.example.com/websocket", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if allowed := policy.Allowed(r); allowed != expected {
+			t.Errorf("origin %q: expected allowed=%v, got %v", origin, expected, allowed)
+		}
+	}
+}
+
+func TestOriginPolicyDevModeAllowsEverything(t *testing.T) {
+	policy, _ := NewOriginPolicy(nil, true)
+
+	r := httptest.NewRequest("GET", "http://api.example.com/websocket", nil)
+	r.Header.Set("Origin", "https://anything.test")
+	if !policy.Allowed(r) {
+		t.Fatal("expected dev mode to allow every origin")
+	}
+}
+
+func TestOriginPolicyRejectsBadPatterns(t *testing.T) {
+	for _, pattern := range []string{"fiesta.example.com", "https://*", "https://*.com", "https://a.*.example.com", "https://example.com/path"} {
+		if _, err := NewOriginPolicy([]string{pattern}, false); !errors.Is(err, ErrInvalidOrigin) {
+			t.Errorf("pattern %q: expected ErrInvalidOrigin, got %v", pattern, err)
+		}
+	}
+}
+
+func TestCorsMiddlewareOnlyAllowsListedOrigins(t *testing.T) {
+	policy, _ := NewOriginPolicy([]string{"https://fiesta.example.com"}, false)
+	s := &Server{origins: policy}
+	handler := s.corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for origin, expected := range map[string]int{
+		"https://fiesta.example.com": http.StatusNoContent,
+		"https://evil.example.com":   http.StatusForbidden,
+	} {
+		r := httptest.NewRequest(http.MethodOptions, "http://api.example.com/", nil)
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+
+		if w.Code != expected {
+			t.Errorf("origin %s: expected status %d, got %d", origin, expected, w.Code)
+		}
+		allowOrigin := w.Header().Get("Access-Control-Allow-Origin")
+		if (expected == http.StatusNoContent) != (allowOrigin == origin) {
+			t.Errorf("origin %s: unexpected Access-Control-Allow-Origin %q", origin, allowOrigin)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"fiesta_box/internal/database"
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/services"
+)
+
+// savedPrompts is the prompt library, or nil when the server has no database
+// or it is unavailable.
+func (s *Server) savedPrompts() database.PromptRepository {
+	if s.db == nil || !s.dbMonitor.Available() {
+		return nil
+	}
+	return s.db.Prompts()
+}
+
+// createPromptRequest is the body of POST /prompts.
+type createPromptRequest struct {
+	Text     string   `json:"text"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+	Author   string   `json:"author"`
+	// AuthorID saves the prompt to the user signed in with the request.
+	AuthorID string `json:"authorID"`
+}
+
+// savedPrompt checks the request and turns it into a prompt for the library.
+func (req createPromptRequest) savedPrompt() (*prompts.SavedPrompt, error) {
+	text, err := services.NormalizePrompt(req.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	category := strings.TrimSpace(req.Category)
+	if utf8.RuneCountInString(category) > prompts.MaxCategoryLength {
+		return nil, fmt.Errorf("category cannot be longer than %d characters", prompts.MaxCategoryLength)
+	}
+
+	tags := prompts.NormalizeTags(req.Tags)
+	if len(tags) > prompts.MaxTags {
+		return nil, fmt.Errorf("a prompt can have at most %d tags", prompts.MaxTags)
+	}
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > prompts.MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, prompts.MaxTagLength)
+		}
+	}
+
+	author := strings.TrimSpace(req.Author)
+	if author != "" {
+		if author, err = services.NormalizeName(author); err != nil {
+			return nil, err
+		}
+	}
+
+	return &prompts.SavedPrompt{Text: text, Category: category, Tags: tags, Author: author}, nil
+}
+
+func (s *Server) listPromptsHandler(w http.ResponseWriter, r *http.Request) {
+	repository := s.savedPrompts()
+	if repository == nil {
+		writePromptError(w, database.ErrUnavailable)
+		return
+	}
+
+	params := r.URL.Query()
+	query := prompts.SavedPromptQuery{
+		Text:     params.Get("q"),
+		Category: params.Get("category"),
+		Tag:      params.Get("tag"),
+		Author:   params.Get("author"),
+		AuthorID: params.Get("authorID"),
+	}
+	var ok bool
+	if query.Limit, query.Offset, ok = pageParams(w, r); !ok {
+		return
+	}
+
+	found, err := repository.Search(r.Context(), query)
+	if err != nil {
+		writePromptError(w, err)
+		return
+	}
+
+	limit, offset := query.Page()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"prompts": found,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+func (s *Server) createPromptHandler(w http.ResponseWriter, r *http.Request) {
+	repository := s.savedPrompts()
+	if repository == nil {
+		writePromptError(w, database.ErrUnavailable)
+		return
+	}
+
+	var req createPromptRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "body must be a JSON object with text, category, tags, author and authorID"})
+		return
+	}
+
+	prompt, err := req.savedPrompt()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	if req.AuthorID != "" {
+		author, err := s.authenticate(r, req.AuthorID)
+		if err != nil {
+			writeUserError(w, err)
+			return
+		}
+		prompt.AuthorID = author.ID
+		if prompt.Author == "" {
+			prompt.Author = author.DisplayName
+		}
+	}
+
+	if err := repository.Create(r.Context(), prompt); err != nil {
+		writePromptError(w, err)
+		return
+	}
+	log.Printf("Saved prompt %s to the library", prompt.ID)
+
+	writeJSON(w, http.StatusCreated, prompt)
+}
+
+func (s *Server) getPromptHandler(w http.ResponseWriter, r *http.Request) {
+	repository := s.savedPrompts()
+	if repository == nil {
+		writePromptError(w, database.ErrUnavailable)
+		return
+	}
+
+	prompt, err := repository.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writePromptError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, prompt)
+}
+
+func (s *Server) deletePromptHandler(w http.ResponseWriter, r *http.Request) {
+	repository := s.savedPrompts()
+	if repository == nil {
+		writePromptError(w, database.ErrUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	prompt, err := repository.Get(r.Context(), id)
+	if err != nil {
+		writePromptError(w, err)
+		return
+	}
+	// only the author can delete a prompt saved to their account
+	if prompt.AuthorID != "" {
+		if _, err := s.authenticate(r, prompt.AuthorID); err != nil {
+			writeUserError(w, err)
+			return
+		}
+	}
+
+	if err := repository.Delete(r.Context(), id); err != nil {
+		writePromptError(w, err)
+		return
+	}
+	log.Printf("Deleted saved prompt %s from the library", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pageParams reads the optional limit and offset query parameters, replying
+// 400 when either isn't a whole number.
+func pageParams(w http.ResponseWriter, r *http.Request) (limit int, offset int, ok bool) {
+	params := r.URL.Query()
+	for name, target := range map[string]*int{"limit": &limit, "offset": &offset} {
+		value := params.Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": fmt.Sprintf("%s must be a whole number", name)})
+			return 0, 0, false
+		}
+		*target = n
+	}
+	return limit, offset, true
+}
+
+// writePromptError reports an error from the prompt library without leaking
+// database details to the client.
+func writePromptError(w http.ResponseWriter, err error) {
+	if errors.Is(err, database.ErrPromptNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": err.Error()})
+		return
+	}
+	if !errors.Is(err, database.ErrUnavailable) {
+		log.Printf("Prompt library error: %v", err)
+	}
+	writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "the prompt library is unavailable"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	jsonResp, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("error handling JSON marshal. Err: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(jsonResp)
+}
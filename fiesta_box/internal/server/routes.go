@@ -1,19 +1,28 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
-	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 
 	"fiesta_box/internal/handlers"
 	"fiesta_box/internal/models/messages"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
 )
 
-var upgrader = websocket.Upgrader{}
+// upgrader accepts websockets from the origins the server allows.
+func (s *Server) upgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		// newest first, so clients offering several versions get the newest
+		Subprotocols: messages.Subprotocols(),
+		CheckOrigin: s.origins.CheckOrigin,
+	}
+}
 
 func (s *Server) RegisterRoutes() http.Handler {
 	r := mux.NewRouter()
@@ -22,9 +31,20 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Use(s.corsMiddleware)
 
 	r.HandleFunc("/", s.healthHandler)
+	r.HandleFunc("/health/live", s.livenessHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/health/ready", s.readinessHandler).Methods(http.MethodGet, http.MethodOptions)
 
 	r.HandleFunc("/games/health", s.gameServiceHealthHandler)
 
+	r.HandleFunc("/prompts", s.listPromptsHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/prompts", s.createPromptHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/prompts/{id}", s.getPromptHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/prompts/{id}", s.deletePromptHandler).Methods(http.MethodDelete, http.MethodOptions)
+
+	r.HandleFunc("/users", s.createUserHandler).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/users/{id}", s.getUserHandler).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/users/{id}/history", s.userHistoryHandler).Methods(http.MethodGet, http.MethodOptions)
+
 	// Register websocket message handlers
 	handlers.RegisterHandler(messages.MessageTypeStartGame, handlers.StartGameHandler)
 	handlers.RegisterHandler(messages.MessageTypeTransferMaster, handlers.TransferMasterHandler)
@@ -38,6 +58,28 @@ func (s *Server) RegisterRoutes() http.Handler {
 	handlers.RegisterHandler(messages.MessageTypeJoinGame, handlers.JoinGameHandler)
 	handlers.RegisterHandler(messages.MessageTypeLeaveGame, handlers.LeaveGameHandler)
 	handlers.RegisterHandler(messages.MessageTypeCreateGame, handlers.CreateGameHandler)
+	handlers.RegisterHandler(messages.MessageTypeConfigureRoundCount, handlers.ConfigureRoundCountHandler)
+	handlers.RegisterHandler(messages.MessageTypeGetResults, handlers.GetResultsHandler)
+	handlers.RegisterHandler(messages.MessageTypeResumeSession, handlers.ResumeSessionHandler)
+	handlers.RegisterHandler(messages.MessageTypeHello, handlers.HelloHandler)
+
+	// Register websocket message payloads
+	handlers.RegisterPayload(messages.MessageTypeStartGame, messages.DecoderFor[messages.RoomPayload]())
+	handlers.RegisterPayload(messages.MessageTypeTransferMaster, messages.DecoderFor[messages.TransferMasterPayload]())
+	handlers.RegisterPayload(messages.MessageTypeConfigurePromptCount, messages.DecoderFor[messages.PromptCountPayload]())
+	handlers.RegisterPayload(messages.MessageTypeUseSavedPrompt, messages.DecoderFor[messages.PromptPayload]())
+	handlers.RegisterPayload(messages.MessageTypeWritePrompt, messages.DecoderFor[messages.WritePromptPayload]())
+	handlers.RegisterPayload(messages.MessageTypeReceivePrompt, messages.DecoderFor[messages.RoomPayload]())
+	handlers.RegisterPayload(messages.MessageTypePerformPrompt, messages.DecoderFor[messages.PromptPayload]())
+	handlers.RegisterPayload(messages.MessageTypeDrinkForPrompt, messages.DecoderFor[messages.PromptPayload]())
+	handlers.RegisterPayload(messages.MessageTypeChangePlayerName, messages.DecoderFor[messages.ChangePlayerNamePayload]())
+	handlers.RegisterPayload(messages.MessageTypeJoinGame, messages.DecoderFor[messages.JoinGamePayload]())
+	handlers.RegisterPayload(messages.MessageTypeLeaveGame, messages.DecoderFor[messages.RoomPayload]())
+	handlers.RegisterPayload(messages.MessageTypeCreateGame, messages.DecoderFor[messages.CreateGamePayload]())
+	handlers.RegisterPayload(messages.MessageTypeConfigureRoundCount, messages.DecoderFor[messages.RoundCountPayload]())
+	handlers.RegisterPayload(messages.MessageTypeGetResults, messages.DecoderFor[messages.RoomPayload]())
+	handlers.RegisterPayload(messages.MessageTypeResumeSession, messages.DecoderFor[messages.ResumeSessionPayload]())
+	handlers.RegisterPayload(messages.MessageTypeHello, messages.DecoderFor[messages.HelloPayload]())
 
 	r.HandleFunc("/websocket", s.websocketHandler)
 
@@ -50,14 +92,28 @@ func (s *Server) RegisterRoutes() http.Handler {
 // CORS middleware
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := s.origins.Allowed(r)
+
 		// CORS Headers
-		w.Header().Set("Access-Control-Allow-Origin", "*") // Wildcard allows all origins
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type")
-		w.Header().Set("Access-Control-Allow-Credentials", "false") // Credentials not allowed with wildcard origins
+		w.Header().Add("Vary", "Origin")
+		if origin != "" && allowed {
+			if s.origins != nil && s.origins.AllowAll {
+				w.Header().Set("Access-Control-Allow-Origin", "*") // Wildcard allows all origins
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type")
+			w.Header().Set("Access-Control-Allow-Credentials", "false")
+		}
 
 		// Handle preflight OPTIONS requests
 		if r.Method == http.MethodOptions {
+			if !allowed {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
@@ -67,94 +123,127 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 }
 
 
+// healthHandler pings the database and reports 503 while it is down.
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
-	jsonResp, err := json.Marshal(s.db.Health())
-
-	if err != nil {
-		log.Fatalf("error handling JSON marshal. Err: %v", err)
+	health := s.db.Health()
+	status := http.StatusOK
+	if health["status"] != "up" {
+		status = http.StatusServiceUnavailable
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write(jsonResp)
+	writeJSON(w, status, health)
 }
 
 func (s *Server) gameServiceHealthHandler(w http.ResponseWriter, r *http.Request) {
-	jsonResp, err := json.Marshal(s.game.ServiceHealth())
+	writeJSON(w, http.StatusOK, s.game.ServiceHealth())
+}
 
-	if err != nil {
-		log.Fatalf("error handling JSON marshal. Err: %v", err)
+// negotiateSubprotocol picks the protocol version from the subprotocols the
+// client offered. Clients that offer none are assumed to speak the oldest
+// supported version and can switch with a hello message.
+func negotiateSubprotocol(r *http.Request) (messages.ProtocolVersion, bool) {
+	offered := websocket.Subprotocols(r)
+	if len(offered) == 0 {
+		return messages.OldestProtocol(), true
 	}
 
+	versions := []messages.ProtocolVersion{}
+	for _, name := range offered {
+		if version, ok := messages.ParseSubprotocol(name); ok {
+			versions = append(versions, version)
+		}
+	}
+	return messages.NegotiateProtocol(versions)
+}
+
+// rejectProtocol tells a client that offered only unsupported subprotocols
+// which ones the server speaks, before any upgrade happens.
+func rejectProtocol(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Rejected websocket with unsupported subprotocols %v", websocket.Subprotocols(r))
+
+	jsonResp, _ := json.Marshal(map[string]interface{}{
+		"message": "None of the offered protocol versions are supported by this server",
+		"code": responses.CodeUnsupportedProtocol,
+		"supported": messages.SupportedProtocols,
+		"subprotocols": messages.Subprotocols(),
+	})
+
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUpgradeRequired)
 	_, _ = w.Write(jsonResp)
 }
 
 func (s *Server) websocketHandler(w http.ResponseWriter, r *http.Request) {
-	c, err := upgrader.Upgrade(w, r, nil)
+	protocol, ok := negotiateSubprotocol(r)
+	if !ok {
+		rejectProtocol(w, r)
+		return
+	}
+
+	conn, err := s.upgrader().Upgrade(w, r, nil)
 
 	if err != nil {
 		log.Printf("could not open websocket: %v", err)
-		_, _ = w.Write([]byte("could not open websocket"))
-		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	c := sockets.NewClientWithConfig(conn, s.config.Socket)
+	session := handlers.NewSession(protocol)
+	log.Printf("Websocket connected speaking protocol version %d", protocol)
+
+	// Only the write pump writes to the connection; it also keeps the
+	// connection alive with periodic pings
+	go c.WritePump()
 	defer c.Close()
+	defer s.game.Disconnect(c)
 
 	// Handle websocket connection
+	for {
+		message, err := c.ReadMessage()
+		if err != nil {
+			log.Println("Error on reading message from client:", err)
+			break
+		}
+		log.Printf("Received from client the message: %s", message)
 
-	go func() {
-		for {
-			mt, message, err := c.ReadMessage()
-			if err != nil {
-				log.Println("Error on reading message from client:", err)
-				break
-			}
-			log.Printf("Received from client the message: %s", message)
-	
-			// Determine message type
-			var clientMsg messages.Message
-			err = json.Unmarshal(message, &clientMsg)
-	
-			if err != nil {
-				log.Println("Error on parsing JSON message from client:", err)
-				break
-			}
-	
-			handlerArgs := handlers.HandlerFuncArgs{
-				Message: clientMsg,
-				GameService: s.game,
-				Client: c,
-			}
-	
-			response, err := handlers.HandleMessage(handlerArgs)
-			if err != nil {
-				log.Println("Error on handling message from client:", err)
-				continue
-			}
-	
-			responseJson, err := json.Marshal(response)
-			if err != nil {
-				log.Println("Error on parsing JSON message for response:", err)
-				continue
-			}
-	
-			err = c.WriteMessage(mt, responseJson)
-			if err != nil {
-				log.Println("Error on writing response to client:", err)
+		// Determine message type
+		var clientMsg messages.Message
+		err = json.Unmarshal(message, &clientMsg)
+
+		if err != nil {
+			// one bad frame should not cost the player their seat
+			log.Println("Error on parsing JSON message from client:", err)
+			if err := c.SendJSON(handlers.MalformedMessage(err)); err != nil {
+				log.Println("Error on sending response to client:", err)
 				break
 			}
+			continue
 		}
-	}()
 
-	// Keep the connection alive with periodic pings
-	for {
-		if err := c.WriteMessage(websocket.PingMessage, nil); err != nil {
-			log.Printf("Ping failed: %v", err)
+		ctx, cancel := context.WithTimeout(r.Context(), s.config.Server.MessageTimeout)
+		handlerArgs := handlers.HandlerFuncArgs{
+			Context: ctx,
+			Message: clientMsg,
+			GameService: s.game,
+			Client: c,
+			Session: session,
+			SavedPrompts: s.savedPrompts(),
+			Users: s.userService(),
+		}
+
+		response, err := handlers.HandleMessage(handlerArgs)
+		cancel()
+		if err != nil {
+			// the client still gets an error reply for its requestId
+			log.Println("Error on handling message from client:", err)
+		}
+
+		if err := c.SendJSON(response); err != nil {
+			log.Println("Error on sending response to client:", err)
+			break
+		}
+
+		if session.Closing {
 			break
 		}
-		time.Sleep(30 * time.Second) // Send a ping every 30 seconds
 	}
-	
-	
 }
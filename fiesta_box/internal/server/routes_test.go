@@ -3,11 +3,20 @@ package server
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"fiesta_box/internal/config"
+	"fiesta_box/internal/database"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/services"
 )
 
 func TestHandler(t *testing.T) {
-	s := &Server{}
+	s := &Server{db: database.New(database.Config{Driver: database.DriverMemory})}
 	server := httptest.NewServer(http.HandlerFunc(s.healthHandler))
 	defer server.Close()
 	resp, err := http.Get(server.URL)
@@ -28,3 +37,34 @@ func TestHandler(t *testing.T) {
 	// 	t.Errorf("expected response body to be %v; got %v", expected, string(body))
 	// }
 }
+
+func TestWebsocketKeepsReadingAfterMalformedFrame(t *testing.T) {
+	s := &Server{game: services.NewGameService(), config: config.Default()}
+	server := httptest.NewServer(s.RegisterRoutes())
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/websocket", nil)
+	if err != nil {
+		t.Fatalf("could not connect: %v", err)
+	}
+	defer conn.Close()
+
+	read := func() responses.SocketResponse {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var response responses.SocketResponse
+		if err := conn.ReadJSON(&response); err != nil {
+			t.Fatalf("expected a reply: %v", err)
+		}
+		return response
+	}
+
+	conn.WriteMessage(websocket.TextMessage, []byte("{not json"))
+	if response := read(); response.Error == nil || response.Error.Code != responses.CodeInvalidPayload {
+		t.Fatalf("expected an invalid_payload error, got %+v", response)
+	}
+
+	conn.WriteJSON(map[string]interface{}{"type": "no_such_type", "requestId": "after"})
+	if response := read(); response.RequestID != "after" || response.Error == nil || response.Error.Code != responses.CodeUnknownMessageType {
+		t.Fatalf("expected the connection to keep reading, got %+v", response)
+	}
+}
@@ -1,44 +1,89 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"os"
-	"strconv"
 	"time"
 
-	_ "github.com/joho/godotenv/autoload"
-
+	"fiesta_box/internal/config"
 	"fiesta_box/internal/database"
 	"fiesta_box/internal/services"
 )
 
 type Server struct {
 	port int
+	config *config.Config
 	db database.Service
+	dbMonitor *databaseMonitor
 	game *services.GameService
+	origins *OriginPolicy
 }
 
-func NewServer() *http.Server {
-	port, _ := strconv.Atoi(os.Getenv("PORT"))
+func NewServer(cfg *config.Config) (*http.Server, error) {
+	origins, err := NewOriginPolicy(cfg.CORS.AllowedOrigins, cfg.CORS.DevMode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid origin allowlist: %w", err)
+	}
+	if origins.AllowAll {
+		log.Print("CORS_DEV_MODE is on, every origin is allowed")
+	}
+
+	gameService := services.NewGameServiceWithConfig(cfg.Game)
+	gameService.StartReaper(cfg.Reaper)
 
-	gameService := services.NewGameService()	
+	// the first check migrates before serving when the database is up; when it
+	// isn't, the server starts anyway and the monitor keeps trying
+	db := database.New(cfg.Database)
+	dbMonitor := newDatabaseMonitor(db, cfg.Database.AutoMigrate)
+	dbMonitor.check()
+	dbMonitor.Start(cfg.Database.HealthInterval)
 
 	NewServer := &Server{
-		port: port,
+		port: cfg.Server.Port,
+		config: cfg,
 
-		db: database.New(),
+		db: db,
+		dbMonitor: dbMonitor,
 		game: gameService,
+		origins: origins,
 	}
+	gameService.RecordResultsWith(NewServer.recordResults)
 
 	// Declare Server config
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", NewServer.port),
 		Handler:      NewServer.RegisterRoutes(),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
 	}
 
-	return server
+	return server, nil
+}
+
+// migrationTimeout bounds how long a migration run waits, including waiting
+// for another server that is migrating at the same time.
+const migrationTimeout = time.Minute
+
+// migrateDatabase brings the schema up to date.
+func migrateDatabase(db database.Service) error {
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+
+	migrator, err := db.Migrator()
+	if errors.Is(err, database.ErrNoSchema) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not load migrations: %w", err)
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("could not migrate database: %w", err)
+	}
+	log.Printf("Database schema is up to date, applied %d migrations", len(applied))
+	return nil
 }
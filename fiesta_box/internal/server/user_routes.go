@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"fiesta_box/internal/database"
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/users"
+	"fiesta_box/internal/services"
+)
+
+// userService signs players in to their accounts, or is nil when the server
+// has no database or it is unavailable.
+func (s *Server) userService() *services.UserService {
+	if s.db == nil || !s.dbMonitor.Available() {
+		return nil
+	}
+	return services.NewUserService(s.db.Users())
+}
+
+// createUserRequest is the body of POST /users.
+type createUserRequest struct {
+	DisplayName string `json:"displayName"`
+	AvatarURL   string `json:"avatarURL"`
+}
+
+func (s *Server) createUserHandler(w http.ResponseWriter, r *http.Request) {
+	accounts := s.userService()
+	if accounts == nil {
+		writeUserError(w, database.ErrUnavailable)
+		return
+	}
+
+	var req createUserRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "body must be a JSON object with displayName and avatarURL"})
+		return
+	}
+
+	user, token, err := accounts.CreateUser(r.Context(), req.DisplayName, req.AvatarURL)
+	if errors.Is(err, services.ErrInvalidName) || errors.Is(err, users.ErrInvalidAvatarURL) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	// the token is only ever shown here
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"user":  user,
+		"token": token,
+	})
+}
+
+func (s *Server) getUserHandler(w http.ResponseWriter, r *http.Request) {
+	accounts := s.userService()
+	if accounts == nil {
+		writeUserError(w, database.ErrUnavailable)
+		return
+	}
+
+	user, err := accounts.GetUser(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (s *Server) userHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	accounts := s.userService()
+	if accounts == nil {
+		writeUserError(w, database.ErrUnavailable)
+		return
+	}
+
+	limit, offset, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	totals, history, err := accounts.History(r.Context(), id, limit, offset)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	limit, offset = users.HistoryPage(limit, offset)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"totals": totals,
+		"games":  history,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// recordResults adds a finished game to the history of the players who
+// played it with their accounts.
+func (s *Server) recordResults(ctx context.Context, results *games.Results) error {
+	accounts := s.userService()
+	if accounts == nil {
+		return database.ErrUnavailable
+	}
+	return accounts.RecordResults(ctx, results)
+}
+
+// authenticate signs the request in as the user with the token from its
+// "Authorization: Bearer" header.
+func (s *Server) authenticate(r *http.Request, userID string) (*users.User, error) {
+	accounts := s.userService()
+	if accounts == nil {
+		return nil, database.ErrUnavailable
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, services.ErrInvalidUserToken
+	}
+	return accounts.Authenticate(r.Context(), userID, strings.TrimSpace(token))
+}
+
+// writeUserError reports an error from the user store without leaking
+// database details to the client.
+func writeUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, database.ErrUserNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "user not found"})
+	case errors.Is(err, services.ErrInvalidUserToken):
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "the user token is not valid"})
+	default:
+		if !errors.Is(err, database.ErrUnavailable) {
+			log.Printf("User store error: %v", err)
+		}
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "user accounts are unavailable"})
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fiesta_box/internal/database"
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/users"
+	"fiesta_box/internal/services"
+)
+
+func TestSavedPromptsFollowTheirAuthor(t *testing.T) {
+	s := &Server{db: database.New(database.Config{Driver: database.DriverMemory}), game: services.NewGameService()}
+	handler := s.RegisterRoutes()
+
+	request := func(method string, path string, token string, body string, into interface{}) int {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if into != nil {
+			json.Unmarshal(w.Body.Bytes(), into)
+		}
+		return w.Code
+	}
+
+	var created struct {
+		User  map[string]interface{} `json:"user"`
+		Token string                 `json:"token"`
+	}
+	if code := request(http.MethodPost, "/users", "", `{"displayName": "  Ana ", "avatarURL": "https://example.com/ana.png"}`, &created); code != http.StatusCreated {
+		t.Fatalf("expected the user to be created, got %d", code)
+	}
+	id, _ := created.User["id"].(string)
+	if id == "" || created.Token == "" || created.User["displayName"] != "Ana" {
+		t.Fatalf("expected a user and a token, got %+v", created)
+	}
+
+	if code := request(http.MethodPost, "/users", "", `{"displayName": "Ben", "avatarURL": "javascript:alert(1)"}`, nil); code != http.StatusBadRequest {
+		t.Fatalf("expected a bad avatar to be rejected, got %d", code)
+	}
+
+	var user map[string]interface{}
+	if code := request(http.MethodGet, "/users/"+id, "", "", &user); code != http.StatusOK || user["displayName"] != "Ana" {
+		t.Fatalf("expected to look the user up, got %d %v", code, user)
+	}
+	if _, ok := user["tokenHash"]; ok {
+		t.Fatalf("the token hash must not be shown, got %v", user)
+	}
+
+	prompt := `{"text": "Sing a song", "authorID": "` + id + `"}`
+	if code := request(http.MethodPost, "/prompts", "guess", prompt, nil); code != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong token to be refused, got %d", code)
+	}
+	var saved map[string]interface{}
+	if code := request(http.MethodPost, "/prompts", created.Token, prompt, &saved); code != http.StatusCreated {
+		t.Fatalf("expected the prompt to be saved, got %d", code)
+	}
+	if saved["authorID"] != id || saved["author"] != "Ana" {
+		t.Fatalf("expected the prompt to be saved to Ana, got %v", saved)
+	}
+
+	promptID, _ := saved["id"].(string)
+	for _, token := range []string{"", "guess"} {
+		if code := request(http.MethodDelete, "/prompts/"+promptID, token, "", nil); code != http.StatusUnauthorized {
+			t.Fatalf("expected deleting with token %q to be refused, got %d", token, code)
+		}
+	}
+
+	var listed struct {
+		Prompts []map[string]interface{} `json:"prompts"`
+	}
+	if code := request(http.MethodGet, "/prompts?authorID="+id, "", "", &listed); code != http.StatusOK || len(listed.Prompts) != 1 {
+		t.Fatalf("expected Ana's prompt, got %d %+v", code, listed)
+	}
+
+	if code := request(http.MethodDelete, "/prompts/"+promptID, created.Token, "", nil); code != http.StatusNoContent {
+		t.Fatalf("expected Ana to delete her prompt, got %d", code)
+	}
+	if code := request(http.MethodGet, "/prompts/"+promptID, "", "", nil); code != http.StatusNotFound {
+		t.Fatalf("expected the prompt to be gone, got %d", code)
+	}
+}
+
+func TestUserHistory(t *testing.T) {
+	s := &Server{db: database.New(database.Config{Driver: database.DriverMemory}), game: services.NewGameService()}
+	handler := s.RegisterRoutes()
+
+	user, _, err := s.userService().CreateUser(context.Background(), "Ana", "")
+	if err != nil {
+		t.Fatalf("could not create user: %v", err)
+	}
+	results := &games.Results{
+		GameID:      "game-1",
+		Room:        "ROOM",
+		Rounds:      2,
+		Players:     []games.PlayerResult{{UserID: user.ID, Registered: true, Name: "Ana", Performed: 2, Drank: 1}},
+		CompletedAt: time.Now(),
+	}
+	if err := s.recordResults(context.Background(), results); err != nil {
+		t.Fatalf("could not record results: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/"+user.ID+"/history?limit=5", nil))
+	var history struct {
+		Totals users.Totals       `json:"totals"`
+		Games  []users.GameRecord `json:"games"`
+		Limit  int                `json:"limit"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &history)
+	if w.Code != http.StatusOK || history.Totals.Games != 1 || len(history.Games) != 1 || history.Games[0].Room != "ROOM" || history.Limit != 5 {
+		t.Fatalf("expected Ana's game, got %d %+v", w.Code, history)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/nobody/history", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown user, got %d", w.Code)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"fiesta_box/internal/models/prompts"
+)
+
+var (
+	ErrWaitForRound  = errors.New("wait for everyone else to get a prompt this round")
+	ErrNoPromptsLeft = errors.New("there are no prompts left for you")
+)
+
+// Dealer hands prompts from a game's pool to its players. A player is never
+// dealt a prompt they wrote, a prompt is never dealt twice, and a player gets
+// at most one prompt per round. Moving the pool on to the next round is left
+// to the caller.
+type Dealer struct {
+	rng   *rand.Rand
+	mutex sync.Mutex // mutex around rng
+}
+
+// NewDealer creates a dealer whose choices are fully determined by the seed.
+func NewDealer(seed int64) *Dealer {
+	return &Dealer{
+		rng: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func newTimeSeededDealer() *Dealer {
+	return NewDealer(time.Now().UnixNano())
+}
+
+// Deal gives the player a prompt from the pool. players are the user ids of
+// everyone still taking part in the game, in seat order. If the player already
+// holds a prompt that prompt is returned again rather than dealing a new one.
+//
+// Every player is owed PerPlayer prompts over the game. The prompt is drawn
+// at random, except when another player could only still be served by
+// prompts someone else wrote; then their prompts are dealt first so that
+// nobody is left with only their own prompts in the pool.
+func (d *Dealer) Deal(pool *prompts.Pool, playerID string, players []string) (*prompts.Prompt, error) {
+	if id, ok := pool.Held[playerID]; ok {
+		return pool.Prompts[id], nil
+	}
+	if pool.LastRound[playerID] >= pool.Round {
+		return nil, ErrWaitForRound
+	}
+
+	received := receivedCounts(pool)
+	if received[playerID] >= pool.PerPlayer {
+		return nil, ErrNoPromptsLeft
+	}
+
+	undealt := 0
+	remaining := map[string]int{}
+	for id, prompt := range pool.Prompts {
+		if _, dealt := pool.DealtTo[id]; !dealt {
+			remaining[prompt.AuthorID]++
+			undealt++
+		}
+	}
+
+	// a player is tight when everything undealt that they did not write is
+	// exactly what they are still owed. Ties go to the player seated first.
+	author := ""
+	for _, player := range players {
+		owed := pool.PerPlayer - received[player]
+		if player != playerID && owed > 0 && owed >= undealt-remaining[player] {
+			author = player
+			break
+		}
+	}
+
+	candidates := eligiblePrompts(pool, playerID, author)
+	if len(candidates) == 0 && author != "" {
+		candidates = eligiblePrompts(pool, playerID, "")
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoPromptsLeft
+	}
+
+	d.mutex.Lock()
+	prompt := candidates[d.rng.Intn(len(candidates))]
+	d.mutex.Unlock()
+
+	pool.DealtTo[prompt.ID] = playerID
+	pool.Held[playerID] = prompt.ID
+	pool.LastRound[playerID] = pool.Round
+
+	return prompt, nil
+}
+
+// eligiblePrompts lists the undealt prompts the player did not write in the
+// order they were written, so a seeded choice depends neither on map order nor
+// on the random prompt ids. If author is set only prompts by that author are
+// listed.
+func eligiblePrompts(pool *prompts.Pool, playerID string, author string) []*prompts.Prompt {
+	candidates := []*prompts.Prompt{}
+	for id, prompt := range pool.Prompts {
+		if _, dealt := pool.DealtTo[id]; dealt || prompt.AuthorID == playerID {
+			continue
+		}
+		if author != "" && prompt.AuthorID != author {
+			continue
+		}
+		candidates = append(candidates, prompt)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Seq < candidates[j].Seq
+	})
+	return candidates
+}
+
+// canDeal reports whether the player is still owed prompts and there is an
+// undealt prompt they did not write.
+func canDeal(pool *prompts.Pool, playerID string) bool {
+	if receivedCounts(pool)[playerID] >= pool.PerPlayer {
+		return false
+	}
+	return len(eligiblePrompts(pool, playerID, "")) > 0
+}
+
+// receivedCounts counts how many prompts each player has been dealt.
+func receivedCounts(pool *prompts.Pool) map[string]int {
+	received := map[string]int{}
+	for _, player := range pool.DealtTo {
+		received[player]++
+	}
+	return received
+}
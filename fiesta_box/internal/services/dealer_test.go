@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/sockets"
+)
+
+func newTestPool(players []string, perPlayer int) *prompts.Pool {
+	pool := prompts.NewPool()
+	pool.PerPlayer = perPlayer
+	for _, player := range players {
+		for i := 0; i < perPlayer; i++ {
+			pool.Add(&prompts.Prompt{
+				ID:       fmt.Sprintf("%s-%d", player, i),
+				Text:     fmt.Sprintf("prompt %d by %s", i, player),
+				AuthorID: player,
+			})
+		}
+	}
+	pool.Ready = true
+	return &pool
+}
+
+// dealAll deals and resolves prompts round by round until nobody can be dealt.
+// It moves the pool on to the next round itself, as the game service does.
+func dealAll(t *testing.T, dealer *Dealer, pool *prompts.Pool, players []string) []string {
+	t.Helper()
+
+	deals := []string{}
+	for {
+		dealt := false
+		for _, player := range players {
+			prompt, err := dealer.Deal(pool, player, players)
+			if errors.Is(err, ErrWaitForRound) || errors.Is(err, ErrNoPromptsLeft) {
+				continue
+			}
+			if err != nil {
+				t.Fatalf("unexpected error dealing to %s: %v", player, err)
+			}
+			deals = append(deals, player+":"+prompt.ID)
+			delete(pool.Held, player)
+			dealt = true
+		}
+		if !dealt {
+			return deals
+		}
+		pool.Round++
+	}
+}
+
+func TestDealNeverGivesOwnPrompt(t *testing.T) {
+	players := []string{"ann", "bob", "cat"}
+	pool := newTestPool(players, 3)
+
+	dealAll(t, NewDealer(1), pool, players)
+
+	for id, player := range pool.DealtTo {
+		if pool.Prompts[id].AuthorID == player {
+			t.Fatalf("prompt %s was dealt to its author %s", id, player)
+		}
+	}
+}
+
+func TestDealNeverRepeatsAndUsesWholePool(t *testing.T) {
+	players := []string{"ann", "bob", "cat", "dan"}
+	pool := newTestPool(players, 2)
+
+	deals := dealAll(t, NewDealer(7), pool, players)
+
+	if len(deals) != len(pool.Prompts) {
+		t.Fatalf("expected %d deals, got %d: %v", len(pool.Prompts), len(deals), deals)
+	}
+	if pool.Undealt() != 0 {
+		t.Fatalf("expected no undealt prompts, got %d", pool.Undealt())
+	}
+}
+
+func TestDealOnePromptPerRound(t *testing.T) {
+	players := []string{"ann", "bob"}
+	pool := newTestPool(players, 2)
+	dealer := NewDealer(3)
+
+	if _, err := dealer.Deal(pool, "ann", players); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	delete(pool.Held, "ann")
+
+	if _, err := dealer.Deal(pool, "ann", players); !errors.Is(err, ErrWaitForRound) {
+		t.Fatalf("expected ErrWaitForRound, got %v", err)
+	}
+
+	pool.Round++
+	if _, err := dealer.Deal(pool, "ann", players); err != nil {
+		t.Fatalf("expected a deal in the next round, got %v", err)
+	}
+}
+
+func TestDealReturnsHeldPrompt(t *testing.T) {
+	players := []string{"ann", "bob"}
+	pool := newTestPool(players, 2)
+	dealer := NewDealer(5)
+
+	first, err := dealer.Deal(pool, "ann", players)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, err := dealer.Deal(pool, "ann", players)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.ID != again.ID {
+		t.Fatalf("expected held prompt %s, got %s", first.ID, again.ID)
+	}
+}
+
+func TestDealIsDeterministicForSeed(t *testing.T) {
+	players := []string{"ann", "bob", "cat"}
+
+	first := dealAll(t, NewDealer(42), newTestPool(players, 3), players)
+	second := dealAll(t, NewDealer(42), newTestPool(players, 3), players)
+
+	if fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Fatalf("expected identical deals for the same seed\n%v\n%v", first, second)
+	}
+}
+
+// playGame deals and resolves a whole game through a service with a seeded
+// dealer and returns who was dealt which prompt. Only the first two players
+// wrote prompts, so more than one player is often short of prompts at once.
+func playGame(t *testing.T, seed int64, players []string) []string {
+	t.Helper()
+
+	game := newTestGame("ROOM", time.Now())
+	game.Phase = games.PhaseDealing
+	game.Prompts = *newTestPool(players[:2], 2)
+	game.Scores = map[string]*games.Score{}
+	conns := make([]*sockets.Client, len(players))
+	for i, player := range players {
+		conns[i] = &sockets.Client{}
+		game.Clients[conns[i]] = &games.GameClient{Room: "ROOM", UserID: player, Name: player, Connected: true}
+	}
+
+	s := NewGameServiceWithDealer(DefaultGameConfig, NewDealer(seed))
+	s.games = map[string]*games.Game{"ROOM": game}
+
+	deals := []string{}
+	for game.Phase == games.PhaseDealing {
+		dealt := []*sockets.Client{}
+		for i, c := range conns {
+			deal, err := s.ReceivePrompt(context.Background(), c, "ROOM")
+			if errors.Is(err, ErrWaitForRound) || errors.Is(err, ErrNoPromptsLeft) {
+				continue
+			}
+			if err != nil {
+				t.Fatalf("unexpected error dealing to %s: %v", players[i], err)
+			}
+			deals = append(deals, players[i]+":"+deal.Prompt.ID)
+			dealt = append(dealt, c)
+		}
+		for _, c := range dealt {
+			held := game.Prompts.Held[game.Clients[c].UserID]
+			if err := s.ResolvePrompt(context.Background(), c, "ROOM", held, prompts.Performed); err != nil {
+				t.Fatalf("unexpected error resolving %s: %v", held, err)
+			}
+		}
+	}
+	if game.Phase != games.PhaseCompleted {
+		t.Fatalf("expected the game to complete, it is in the %s phase", game.Phase)
+	}
+	return deals
+}
+
+func TestSameSeedDealsSameGame(t *testing.T) {
+	players := []string{"ann", "bob", "cat"}
+
+	for seed := int64(1); seed <= 5; seed++ {
+		first := playGame(t, seed, players)
+		for i := 0; i < 10; i++ {
+			again := playGame(t, seed, players)
+			if fmt.Sprint(again) != fmt.Sprint(first) {
+				t.Fatalf("expected the same deals for seed %d:\n%v\n%v", seed, first, again)
+			}
+		}
+	}
+}
+
+// playServiceGame writes, deals and performs a whole game through the game
+// service, and returns who was dealt which prompt by name and text, since
+// the ids are random.
+func playServiceGame(t *testing.T, seed int64, names []string) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	s := NewGameServiceWithDealer(DefaultGameConfig, NewDealer(seed))
+	conns := make([]*sockets.Client, len(names))
+	for i := range conns {
+		conns[i] = newTestSocket(t)
+	}
+
+	master, err := s.NewGame(ctx, conns[0], names[0], "")
+	if err != nil {
+		t.Fatalf("could not create game: %v", err)
+	}
+	room := master.Room
+	for i := 1; i < len(names); i++ {
+		if _, err := s.AddToGame(ctx, conns[i], room, names[i], ""); err != nil {
+			t.Fatalf("could not join game: %v", err)
+		}
+	}
+	if err := s.StartGame(ctx, conns[0], room); err != nil {
+		t.Fatalf("could not start game: %v", err)
+	}
+	for i, c := range conns {
+		for j := 0; j < prompts.DefaultPerPlayer; j++ {
+			if _, err := s.WritePrompt(ctx, c, room, fmt.Sprintf("%s's prompt %d", names[i], j)); err != nil {
+				t.Fatalf("could not write prompt: %v", err)
+			}
+		}
+	}
+
+	deals := []string{}
+	for {
+		dealt := map[*sockets.Client]string{}
+		for i, c := range conns {
+			deal, err := s.ReceivePrompt(ctx, c, room)
+			if err != nil {
+				continue
+			}
+			deals = append(deals, names[i]+":"+deal.Prompt.Text)
+			dealt[c] = deal.Prompt.ID
+		}
+		if len(dealt) == 0 {
+			break
+		}
+		for c, promptID := range dealt {
+			if err := s.ResolvePrompt(ctx, c, room, promptID, prompts.Performed); err != nil {
+				t.Fatalf("could not resolve prompt: %v", err)
+			}
+		}
+	}
+
+	if _, err := s.Results(ctx, room); err != nil {
+		t.Fatalf("expected the game to finish: %v", err)
+	}
+	return deals
+}
+
+func TestSameSeedDealsSameServiceGame(t *testing.T) {
+	names := []string{"Ann", "Bob", "Cat"}
+
+	for seed := int64(1); seed <= 3; seed++ {
+		first := playServiceGame(t, seed, names)
+		if len(first) != len(names)*prompts.DefaultPerPlayer {
+			t.Fatalf("expected every prompt to be dealt, got %v", first)
+		}
+		again := playServiceGame(t, seed, names)
+		if fmt.Sprint(again) != fmt.Sprint(first) {
+			t.Fatalf("expected the same deals for seed %d:\n%v\n%v", seed, first, again)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/responses"
+)
+
+// broadcastBuffer is how many events a room can queue before new ones are dropped.
+const broadcastBuffer = 64
+
+// broadcast queues the event for every client in the game room. Every
+// change to a game is broadcast, so this also marks the game as active.
+// It never blocks, so it is safe to call while holding the game lock.
+func (s *GameService) broadcast(game *games.Game, event responses.SocketEvent) {
+	game.LastActivity = time.Now()
+	event.Kind = responses.KindEvent
+
+	select {
+	case game.Broadcast <- event:
+	default:
+		log.Printf("[broadcast] - Game %s broadcast queue is full, dropping: %s", game.Room, event.Message)
+	}
+}
+
+// runHub consumes the game's Broadcast channel and fans each event out to
+// every client in the room. Clients are sent to without blocking, so one slow
+// client cannot hold up the rest of the room; a client that falls too far
+// behind is disconnected by its own send queue.
+func (s *GameService) runHub(game *games.Game) {
+	for event := range game.Broadcast {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("[runHub] - Could not marshal broadcast for game %s: %v", game.Room, err)
+			continue
+		}
+
+		game.Mutex.Lock()
+		clients := make([]*games.GameClient, 0, len(game.Clients))
+		for _, client := range game.Clients {
+			if client.Connected {
+				clients = append(clients, client)
+			}
+		}
+		game.Mutex.Unlock()
+
+		for _, client := range clients {
+			if !client.Client.Send(payload) {
+				log.Printf("[runHub] - Could not send broadcast to client %s in game %s", client.UserID, game.Room)
+			}
+		}
+	}
+}
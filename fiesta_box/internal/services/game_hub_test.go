@@ -0,0 +1,121 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
+)
+
+// seatSocket seats a new websocket client in the game and returns the peer
+// that hears what the room sends it. Its writer only runs when pumping.
+func seatSocket(t *testing.T, game *games.Game, userID string, connected bool, config sockets.Config, pumping bool) (*sockets.Client, *websocket.Conn) {
+	t.Helper()
+
+	c, peer := dialTestSocket(t, config)
+	if pumping {
+		go c.WritePump()
+	}
+	game.Clients[c] = &games.GameClient{Room: game.Room, Client: c, UserID: userID, Connected: connected}
+	return c, peer
+}
+
+// readEvent waits for the next event a peer is sent.
+func readEvent(peer *websocket.Conn, wait time.Duration) (responses.SocketEvent, error) {
+	var event responses.SocketEvent
+	peer.SetReadDeadline(time.Now().Add(wait))
+	_, message, err := peer.ReadMessage()
+	if err != nil {
+		return event, err
+	}
+	err = json.Unmarshal(message, &event)
+	return event, err
+}
+
+func TestBroadcastReachesEveryConnectedClient(t *testing.T) {
+	game := newTestGame("ROOM", time.Now())
+	_, ann := seatSocket(t, game, "ann", true, sockets.DefaultConfig, true)
+	_, bob := seatSocket(t, game, "bob", true, sockets.DefaultConfig, true)
+	_, gone := seatSocket(t, game, "cat", false, sockets.DefaultConfig, true)
+
+	s := NewGameService()
+	go s.runHub(game)
+	defer close(game.Broadcast)
+
+	game.Mutex.Lock()
+	s.broadcast(game, responses.SocketEvent{Event: responses.EventPlayerJoined, Message: "hello"})
+	game.Mutex.Unlock()
+
+	for name, peer := range map[string]*websocket.Conn{"ann": ann, "bob": bob} {
+		event, err := readEvent(peer, 2*time.Second)
+		if err != nil || event.Message != "hello" || event.Kind != responses.KindEvent {
+			t.Fatalf("expected %s to hear the event, got %+v %v", name, event, err)
+		}
+	}
+	if event, err := readEvent(gone, 100*time.Millisecond); err == nil {
+		t.Fatalf("expected a disconnected seat to hear nothing, got %+v", event)
+	}
+}
+
+func TestBroadcastDropsEventsWhenTheQueueIsFull(t *testing.T) {
+	game := newTestGame("ROOM", time.Now())
+	s := NewGameService()
+
+	// no hub is draining the queue
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i <= broadcastBuffer; i++ {
+			s.broadcast(game, responses.SocketEvent{Message: fmt.Sprint(i)})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast blocked on a full queue")
+	}
+	if len(game.Broadcast) != broadcastBuffer {
+		t.Fatalf("expected the queue to stay full at %d, got %d", broadcastBuffer, len(game.Broadcast))
+	}
+	if first := <-game.Broadcast; first.Message != "0" {
+		t.Fatalf("expected the newest event to be dropped, got %q first", first.Message)
+	}
+}
+
+func TestStuckClientDoesNotHoldUpTheRoom(t *testing.T) {
+	game := newTestGame("ROOM", time.Now())
+	stuckConfig := sockets.DefaultConfig
+	stuckConfig.SendBuffer = 1
+	stuck, _ := seatSocket(t, game, "ann", true, stuckConfig, false)
+	_, bob := seatSocket(t, game, "bob", true, sockets.DefaultConfig, true)
+
+	s := NewGameService()
+	go s.runHub(game)
+	defer close(game.Broadcast)
+
+	const events = 5
+	for i := 0; i < events; i++ {
+		game.Mutex.Lock()
+		s.broadcast(game, responses.SocketEvent{Message: fmt.Sprint(i)})
+		game.Mutex.Unlock()
+	}
+
+	for i := 0; i < events; i++ {
+		event, err := readEvent(bob, 2*time.Second)
+		if err != nil || event.Message != fmt.Sprint(i) {
+			t.Fatalf("expected bob to hear event %d, got %+v %v", i, event, err)
+		}
+	}
+	select {
+	case <-stuck.Closed():
+	case <-time.After(time.Second):
+		t.Fatal("expected the stuck client to be disconnected")
+	}
+}
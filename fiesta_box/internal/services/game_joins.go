@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/messages"
+	"fiesta_box/internal/sockets"
+)
+
+var (
+	ErrRoomFull           = errors.New("game room is full")
+	ErrGameAlreadyStarted = errors.New("game has already started")
+	ErrAlreadyInGame      = errors.New("you are already in this game room")
+)
+
+// JoinRejection is a machine-readable reason a join was refused, so clients
+// can tell players why they can't get in.
+type JoinRejection string
+
+const (
+	JoinRoomNotFound  JoinRejection = "room_not_found"
+	JoinRoomFull      JoinRejection = "room_full"
+	JoinGameStarted   JoinRejection = "game_started"
+	JoinAlreadyJoined JoinRejection = "already_joined"
+	JoinNameTaken     JoinRejection = "name_taken"
+	JoinBadAccount    JoinRejection = "bad_account"
+	JoinFailed        JoinRejection = "failed"
+)
+
+// JoinRejectionFor maps an error from joining to the reason reported to the client.
+func JoinRejectionFor(err error) JoinRejection {
+	switch {
+	case errors.Is(err, ErrGameNotFound):
+		return JoinRoomNotFound
+	case errors.Is(err, ErrRoomFull):
+		return JoinRoomFull
+	case errors.Is(err, ErrGameAlreadyStarted):
+		return JoinGameStarted
+	case errors.Is(err, ErrAlreadyInGame):
+		return JoinAlreadyJoined
+	case errors.Is(err, ErrNameTaken):
+		return JoinNameTaken
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrInvalidUserToken):
+		return JoinBadAccount
+	default:
+		return JoinFailed
+	}
+}
+
+// canJoinGame checks that the connection may take a new seat in the game
+// room under the name and user. The caller must hold the game lock.
+func canJoinGame(game *games.Game, c *sockets.Client, name string, userID string) error {
+	if _, ok := game.Clients[c]; ok {
+		return ErrAlreadyInGame
+	}
+	// a user coming back on another connection resumes their seat instead
+	if userID != "" && findClient(game, userID) != nil {
+		return ErrAlreadyInGame
+	}
+	if err := checkPhase(game, messages.MessageTypeJoinGame); err != nil {
+		// late joiners can still get in until the game is wrapping up
+		if !game.LateJoin || game.Phase == games.PhaseResults || game.Phase == games.PhaseCompleted {
+			return ErrGameAlreadyStarted
+		}
+	}
+	if len(game.Clients) >= game.MaxPlayers {
+		return ErrRoomFull
+	}
+	if name != "" && nameTaken(game, name, nil) {
+		return ErrNameTaken
+	}
+	return nil
+}
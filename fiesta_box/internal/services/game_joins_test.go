@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/sockets"
+)
+
+// newJoinableGame is a lobby with the default capacity and Ann seated as user-1.
+func newJoinableGame() (*GameService, *games.Game, *sockets.Client) {
+	ann := &sockets.Client{}
+	game := newTestGame("ROOM", time.Now())
+	game.Phase = games.PhaseLobby
+	game.MaxPlayers = DefaultGameConfig.MaxPlayers
+	game.Clients[ann] = &games.GameClient{Room: "ROOM", Client: ann, UserID: "user-1", Name: "Ann", Connected: true}
+
+	s := NewGameService()
+	s.games = map[string]*games.Game{"ROOM": game}
+	return s, game, ann
+}
+
+func TestJoinRejections(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		setup  func(game *games.Game, ann *sockets.Client) (*sockets.Client, string, string)
+		err    error
+		reason JoinRejection
+	}{
+		{
+			name: "full room",
+			setup: func(game *games.Game, ann *sockets.Client) (*sockets.Client, string, string) {
+				for i := len(game.Clients); i < games.MaxPlayers; i++ {
+					game.Clients[&sockets.Client{}] = &games.GameClient{Room: "ROOM", UserID: fmt.Sprint(i), Name: fmt.Sprint("Player ", i)}
+				}
+				return &sockets.Client{}, "Bob", ""
+			},
+			err:    ErrRoomFull,
+			reason: JoinRoomFull,
+		},
+		{
+			name: "started",
+			setup: func(game *games.Game, ann *sockets.Client) (*sockets.Client, string, string) {
+				game.Phase = games.PhasePlaying
+				return &sockets.Client{}, "Bob", ""
+			},
+			err:    ErrGameAlreadyStarted,
+			reason: JoinGameStarted,
+		},
+		{
+			name: "late join while wrapping up",
+			setup: func(game *games.Game, ann *sockets.Client) (*sockets.Client, string, string) {
+				game.Phase = games.PhaseResults
+				game.LateJoin = true
+				return &sockets.Client{}, "Bob", ""
+			},
+			err:    ErrGameAlreadyStarted,
+			reason: JoinGameStarted,
+		},
+		{
+			name: "same connection",
+			setup: func(game *games.Game, ann *sockets.Client) (*sockets.Client, string, string) {
+				return ann, "Ann again", ""
+			},
+			err:    ErrAlreadyInGame,
+			reason: JoinAlreadyJoined,
+		},
+		{
+			name: "same user",
+			setup: func(game *games.Game, ann *sockets.Client) (*sockets.Client, string, string) {
+				return &sockets.Client{}, "Ann again", "user-1"
+			},
+			err:    ErrAlreadyInGame,
+			reason: JoinAlreadyJoined,
+		},
+		{
+			name: "name taken",
+			setup: func(game *games.Game, ann *sockets.Client) (*sockets.Client, string, string) {
+				return &sockets.Client{}, "ANN", ""
+			},
+			err:    ErrNameTaken,
+			reason: JoinNameTaken,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, game, ann := newJoinableGame()
+			c, name, userID := test.setup(game, ann)
+			seats := len(game.Clients)
+
+			_, err := s.AddToGame(context.Background(), c, "ROOM", name, userID)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if reason := JoinRejectionFor(err); reason != test.reason {
+				t.Fatalf("expected reason %s, got %s", test.reason, reason)
+			}
+			if len(game.Clients) != seats {
+				t.Fatalf("expected no new seat, got %d seats", len(game.Clients))
+			}
+		})
+	}
+}
+
+func TestLateJoin(t *testing.T) {
+	s, game, _ := newJoinableGame()
+	game.Phase = games.PhasePlaying
+	game.LateJoin = true
+
+	client, err := s.AddToGame(context.Background(), &sockets.Client{}, "ROOM", "Bob", "")
+	if err != nil || client.Name != "Bob" {
+		t.Fatalf("expected Bob to join late, got %+v %v", client, err)
+	}
+}
+
+func TestJoinRejectionForOtherErrors(t *testing.T) {
+	for err, reason := range map[error]JoinRejection{
+		ErrGameNotFound:     JoinRoomNotFound,
+		ErrUserNotFound:     JoinBadAccount,
+		ErrInvalidUserToken: JoinBadAccount,
+		errors.New("boom"):  JoinFailed,
+	} {
+		if got := JoinRejectionFor(fmt.Errorf("failed to join: %w", err)); got != reason {
+			t.Errorf("%v: expected %s, got %s", err, reason, got)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
+)
+
+func (s *GameService) TransferMaster(ctx context.Context, c *sockets.Client, room string, userID string) error {
+	// get access to games map
+	log.Print("[TransferMaster] - Getting gameService lock")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer log.Print("[TransferMaster] - Releasing gameService lock")
+
+	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
+	game, ok := s.games[room]
+	if !ok {
+		err := fmt.Errorf("game room %s does not exist - failed to transfer master: %w", room, ErrGameNotFound)
+		log.Print(err.Error())
+		return err
+	}
+
+	// get access to game room
+	log.Printf("[TransferMaster] - Getting game %s lock", game.Room)
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+	defer log.Printf("[TransferMaster] - Releasing game %s lock", game.Room)
+
+	if err := abandoned(ctx, "transfer master", room); err != nil {
+		return err
+	}
+
+	if err := canTransferMaster(game, c, userID); err != nil {
+		err = fmt.Errorf("failed to transfer master of game %s: %w", room, err)
+		log.Print(err.Error())
+		return err
+	}
+
+	s.setMaster(game, userID)
+
+	return nil
+}
+
+// canTransferMaster checks that the client may hand the master role to userID.
+// The caller must hold the game lock.
+func canTransferMaster(game *games.Game, c *sockets.Client, userID string) error {
+	client, ok := game.Clients[c]
+	if !ok {
+		return ErrNotInGame
+	}
+	if client.UserID != game.MasterID {
+		return ErrNotMaster
+	}
+	target := findClient(game, userID)
+	if target == nil {
+		return ErrPlayerNotFound
+	}
+	if !target.Connected {
+		return ErrPlayerDisconnected
+	}
+	return nil
+}
+
+// promoteMaster hands the master role to the longest-seated connected client
+// other than the current master. If nobody is left to promote the game is
+// left without a master. The caller must hold the game lock.
+func (s *GameService) promoteMaster(game *games.Game) {
+	var next *games.GameClient
+	for _, client := range game.Clients {
+		if !client.Connected || client.UserID == game.MasterID {
+			continue
+		}
+		if next == nil || client.JoinedAt.Before(next.JoinedAt) {
+			next = client
+		}
+	}
+
+	if next == nil {
+		log.Printf("[promoteMaster] - No connected players left to promote in game %s", game.Room)
+		game.MasterID = ""
+		return
+	}
+
+	s.setMaster(game, next.UserID)
+}
+
+// setMaster records userID as the game master and tells the room.
+// The caller must hold the game lock.
+func (s *GameService) setMaster(game *games.Game, userID string) {
+	game.MasterID = userID
+
+	message := fmt.Sprintf("Client %s is now the master of game %s", userID, game.Room)
+	log.Print(message)
+
+	s.broadcast(game, responses.SocketEvent{
+		Event:   responses.EventMasterChanged,
+		Message: message,
+		Content: map[string]interface{}{
+			"room":     game.Room,
+			"masterID": userID,
+		},
+	})
+}
+
+// findClient looks up a client in the game room by user id.
+// The caller must hold the game lock.
+func findClient(game *games.Game, userID string) *games.GameClient {
+	for _, client := range game.Clients {
+		if client.UserID == userID {
+			return client
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
+)
+
+// newMasteredGame seats ann as master, then bob who has lost his connection,
+// then cat and dan.
+func newMasteredGame() (*GameService, *games.Game, map[string]*sockets.Client) {
+	game := newTestGame("ROOM", time.Now())
+	game.Phase = games.PhaseLobby
+	game.MasterID = "ann"
+
+	conns := map[string]*sockets.Client{}
+	joined := time.Now()
+	for i, player := range []string{"ann", "bob", "cat", "dan"} {
+		c := &sockets.Client{}
+		conns[player] = c
+		game.Clients[c] = &games.GameClient{
+			Room:      "ROOM",
+			Client:    c,
+			UserID:    player,
+			Name:      player,
+			Connected: player != "bob",
+			JoinedAt:  joined.Add(time.Duration(i) * time.Second),
+		}
+	}
+
+	config := DefaultGameConfig
+	config.ResumeGrace = time.Hour
+	s := NewGameServiceWithConfig(config)
+	s.games = map[string]*games.Game{"ROOM": game}
+	return s, game, conns
+}
+
+func TestCreatorIsMaster(t *testing.T) {
+	s := NewGameService()
+	client, err := s.NewGame(context.Background(), &sockets.Client{}, "Ann", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if game := s.games[client.Room]; game.MasterID != client.UserID {
+		t.Fatalf("expected the creator %s to be master, got %s", client.UserID, game.MasterID)
+	}
+}
+
+func TestTransferMaster(t *testing.T) {
+	for _, test := range []struct {
+		from string
+		to   string
+		err  error
+	}{
+		{"cat", "dan", ErrNotMaster},
+		{"ann", "nobody", ErrPlayerNotFound},
+		{"ann", "bob", ErrPlayerDisconnected},
+	} {
+		s, game, conns := newMasteredGame()
+		if err := s.TransferMaster(context.Background(), conns[test.from], "ROOM", test.to); !errors.Is(err, test.err) {
+			t.Errorf("%s to %s: expected %v, got %v", test.from, test.to, test.err, err)
+		}
+		if game.MasterID != "ann" {
+			t.Errorf("%s to %s: expected ann to stay master, got %s", test.from, test.to, game.MasterID)
+		}
+	}
+
+	s, game, conns := newMasteredGame()
+	if err := s.TransferMaster(context.Background(), conns["ann"], "ROOM", "dan"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	event := <-game.Broadcast
+	if game.MasterID != "dan" || event.Event != responses.EventMasterChanged {
+		t.Fatalf("expected dan to be master and the room told, got %s and %+v", game.MasterID, event)
+	}
+}
+
+func TestMasterIsPromotedWhenTheMasterGoes(t *testing.T) {
+	t.Run("leaves", func(t *testing.T) {
+		s, game, conns := newMasteredGame()
+		if err := s.RemoveFromGame(context.Background(), conns["ann"], "ROOM"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// bob sat down first but can't lead the game without a connection
+		if game.MasterID != "cat" {
+			t.Fatalf("expected cat to be promoted, got %q", game.MasterID)
+		}
+	})
+
+	t.Run("disconnects", func(t *testing.T) {
+		s, game, conns := newMasteredGame()
+		s.Disconnect(conns["ann"])
+		if game.MasterID != "cat" {
+			t.Fatalf("expected cat to be promoted, got %q", game.MasterID)
+		}
+	})
+
+	t.Run("nobody left", func(t *testing.T) {
+		s, game, conns := newMasteredGame()
+		s.Disconnect(conns["cat"])
+		s.Disconnect(conns["dan"])
+		s.Disconnect(conns["ann"])
+		if game.MasterID != "" {
+			t.Fatalf("expected no master with nobody connected, got %q", game.MasterID)
+		}
+	})
+}
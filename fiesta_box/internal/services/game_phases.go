@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/messages"
+	"fiesta_box/internal/models/responses"
+)
+
+var ErrWrongPhase = errors.New("message is not allowed in the current game phase")
+
+// PhaseError is returned when a message arrives while the game is in a phase
+// that does not accept it. It matches ErrWrongPhase with errors.Is.
+type PhaseError struct {
+	Phase       games.Phase
+	MessageType messages.MessageType
+}
+
+func (e *PhaseError) Error() string {
+	return fmt.Sprintf("%s is not allowed during the %s phase", e.MessageType, e.Phase)
+}
+
+func (e *PhaseError) Is(target error) bool {
+	return target == ErrWrongPhase
+}
+
+// checkPhase fails if the game does not accept the message type in its
+// current phase. The caller must hold the game lock.
+func checkPhase(game *games.Game, messageType messages.MessageType) error {
+	if !game.Phase.Allows(messageType) {
+		return &PhaseError{Phase: game.Phase, MessageType: messageType}
+	}
+	return nil
+}
+
+// setPhase moves the game to the next phase and tells the room.
+// The caller must hold the game lock.
+func (s *GameService) setPhase(game *games.Game, next games.Phase) error {
+	if !game.Phase.CanTransitionTo(next) {
+		err := fmt.Errorf("game %s cannot move from the %s phase to the %s phase", game.Room, game.Phase, next)
+		log.Print(err.Error())
+		return err
+	}
+
+	log.Printf("Game %s moved from the %s phase to the %s phase", game.Room, game.Phase, next)
+	game.Phase = next
+	game.Status = next.Status()
+
+	s.broadcast(game, responses.SocketEvent{
+		Event:   responses.EventPhaseChanged,
+		Message: phaseMessage(game),
+		Content: map[string]interface{}{
+			"room":             game.Room,
+			"phase":            game.Phase,
+			"status":           game.Status,
+			"round":            game.Prompts.Round,
+			"players":          len(game.Clients),
+			"promptsPerPlayer": game.Prompts.PerPlayer,
+			"promptsReady":     game.Prompts.Ready,
+		},
+	})
+	return nil
+}
+
+func phaseMessage(game *games.Game) string {
+	switch game.Phase {
+	case games.PhaseWritingPrompts:
+		return fmt.Sprintf("Game %s started with %d players! Everyone write %d prompts", game.Room, len(game.Clients), game.Prompts.PerPlayer)
+	case games.PhaseDealing:
+		return fmt.Sprintf("Round %d: everyone draw a prompt", game.Prompts.Round)
+	case games.PhasePlaying:
+		return fmt.Sprintf("Round %d: perform your prompt or drink", game.Prompts.Round)
+	case games.PhaseResults:
+		return "All prompts have been played"
+	case games.PhaseCompleted:
+		return fmt.Sprintf("Game %s is over", game.Room)
+	default:
+		return fmt.Sprintf("Game %s is in the %s phase", game.Room, game.Phase)
+	}
+}
+
+// advance moves the game on once every connected player is done with the
+// current phase: all prompts written, everyone dealt for the round, or every
+// dealt prompt resolved. The game finishes once the configured rounds have
+// been played or nobody can be dealt another prompt. It is called after
+// anything that could finish a phase, including players leaving. The caller
+// must hold the game lock.
+func (s *GameService) advance(game *games.Game) {
+	// setPhase logs a transition it refuses; the game then stays where it is
+	switch game.Phase {
+	case games.PhaseWritingPrompts:
+		if promptsComplete(game) {
+			game.Prompts.Ready = true
+			if err := s.setPhase(game, games.PhaseDealing); err != nil {
+				game.Prompts.Ready = false
+				return
+			}
+		}
+	case games.PhaseDealing:
+		if roundDealt(game) {
+			if err := s.setPhase(game, games.PhasePlaying); err != nil {
+				return
+			}
+		}
+	case games.PhasePlaying:
+		if !roundResolved(game) {
+			return
+		}
+		if anyDealable(game) && !roundsDone(game) {
+			game.Prompts.Round++
+			if err := s.setPhase(game, games.PhaseDealing); err != nil {
+				game.Prompts.Round--
+				return
+			}
+		} else {
+			s.finish(game)
+		}
+	}
+}
+
+// roundDealt reports whether every connected player has been dealt in the
+// current round, or can no longer be dealt at all. The caller must hold the
+// game lock.
+func roundDealt(game *games.Game) bool {
+	dealt := false
+	for _, client := range game.Clients {
+		if !client.Connected {
+			continue
+		}
+		if game.Prompts.LastRound[client.UserID] >= game.Prompts.Round {
+			dealt = true
+			continue
+		}
+		if canDeal(&game.Prompts, client.UserID) {
+			return false
+		}
+	}
+	return dealt
+}
+
+// roundResolved reports whether no connected player still holds a prompt.
+// The caller must hold the game lock.
+func roundResolved(game *games.Game) bool {
+	for _, client := range game.Clients {
+		if _, held := game.Prompts.Held[client.UserID]; held && client.Connected {
+			return false
+		}
+	}
+	return true
+}
+
+// anyDealable reports whether any connected player can still be dealt a
+// prompt. The caller must hold the game lock.
+func anyDealable(game *games.Game) bool {
+	for _, client := range game.Clients {
+		if client.Connected && canDeal(&game.Prompts, client.UserID) {
+			return true
+		}
+	}
+	return false
+}
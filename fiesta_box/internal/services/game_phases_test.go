@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/messages"
+	"fiesta_box/internal/models/responses"
+)
+
+func TestCheckPhaseRejectsOutOfPhaseMessages(t *testing.T) {
+	game := newTestGame("ROOM", time.Now())
+	game.Phase = games.PhaseLobby
+
+	if err := checkPhase(game, messages.MessageTypeJoinGame); err != nil {
+		t.Fatalf("expected join_game in the lobby, got %v", err)
+	}
+
+	err := checkPhase(game, messages.MessageTypeWritePrompt)
+	if !errors.Is(err, ErrWrongPhase) {
+		t.Fatalf("expected ErrWrongPhase, got %v", err)
+	}
+	var phaseErr *PhaseError
+	if !errors.As(err, &phaseErr) || phaseErr.Phase != games.PhaseLobby || phaseErr.MessageType != messages.MessageTypeWritePrompt {
+		t.Fatalf("expected a PhaseError for write_prompt in the lobby, got %v", err)
+	}
+}
+
+func TestSetPhaseRefusesIllegalTransitions(t *testing.T) {
+	game := newTestGame("ROOM", time.Now())
+	game.Phase = games.PhaseLobby
+
+	s := NewGameService()
+	if err := s.setPhase(game, games.PhaseCompleted); err == nil {
+		t.Fatal("expected the lobby not to move straight to completed")
+	}
+	if game.Phase != games.PhaseLobby || len(game.Broadcast) != 0 {
+		t.Fatalf("expected the game to stay in the lobby quietly, got %s with %d events", game.Phase, len(game.Broadcast))
+	}
+
+	if err := s.setPhase(game, games.PhaseWritingPrompts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	event := <-game.Broadcast
+	if game.Phase != games.PhaseWritingPrompts || game.Status != games.Started || event.Event != responses.EventPhaseChanged {
+		t.Fatalf("expected a phase change to writing prompts, got %s and %+v", game.Phase, event)
+	}
+}
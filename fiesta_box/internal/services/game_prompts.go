@@ -0,0 +1,455 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/messages"
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
+)
+
+var (
+	ErrInvalidPromptCount = fmt.Errorf("prompt count must be between %d and %d", prompts.MinPerPlayer, prompts.MaxPerPlayer)
+	ErrInvalidPrompt      = errors.New("invalid prompt")
+	ErrPromptQuotaReached = errors.New("you have already written all of your prompts")
+	ErrPromptNotHeld      = errors.New("you do not hold that prompt")
+)
+
+// NormalizePrompt trims the prompt text and checks that what is left can be played.
+func NormalizePrompt(text string) (string, error) {
+	text = strings.TrimSpace(text)
+
+	length := utf8.RuneCountInString(text)
+	if length == 0 {
+		return "", fmt.Errorf("%w: prompt cannot be empty", ErrInvalidPrompt)
+	}
+	if length > prompts.MaxTextLength {
+		return "", fmt.Errorf("%w: prompt cannot be longer than %d characters", ErrInvalidPrompt, prompts.MaxTextLength)
+	}
+
+	return text, nil
+}
+
+func (s *GameService) ConfigurePromptCount(ctx context.Context, c *sockets.Client, room string, count int) error {
+	// get access to games map
+	log.Print("[ConfigurePromptCount] - Getting gameService lock")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer log.Print("[ConfigurePromptCount] - Releasing gameService lock")
+
+	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
+	game, ok := s.games[room]
+	if !ok {
+		err := fmt.Errorf("game room %s does not exist - failed to configure prompt count: %w", room, ErrGameNotFound)
+		log.Print(err.Error())
+		return err
+	}
+
+	// get access to game room
+	log.Printf("[ConfigurePromptCount] - Getting game %s lock", game.Room)
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+	defer log.Printf("[ConfigurePromptCount] - Releasing game %s lock", game.Room)
+
+	if err := abandoned(ctx, "configure prompt count", room); err != nil {
+		return err
+	}
+
+	if err := canConfigurePromptCount(game, c, count); err != nil {
+		err = fmt.Errorf("failed to configure prompt count of game %s: %w", room, err)
+		log.Print(err.Error())
+		return err
+	}
+
+	game.Prompts.PerPlayer = count
+
+	message := fmt.Sprintf("Each player will write %d prompts", count)
+	log.Printf("Game %s prompt count configured to %d", room, count)
+
+	s.broadcast(game, responses.SocketEvent{
+		Event:   responses.EventPromptCountChanged,
+		Message: message,
+		Content: map[string]interface{}{
+			"room":             room,
+			"promptsPerPlayer": count,
+		},
+	})
+
+	return nil
+}
+
+// canConfigurePromptCount checks that the client may set the prompt count.
+// The caller must hold the game lock.
+func canConfigurePromptCount(game *games.Game, c *sockets.Client, count int) error {
+	client, ok := game.Clients[c]
+	if !ok {
+		return ErrNotInGame
+	}
+	if client.UserID != game.MasterID {
+		return ErrNotMaster
+	}
+	if err := checkPhase(game, messages.MessageTypeConfigurePromptCount); err != nil {
+		return err
+	}
+	if count < prompts.MinPerPlayer || count > prompts.MaxPerPlayer {
+		return ErrInvalidPromptCount
+	}
+	return nil
+}
+
+func (s *GameService) WritePrompt(ctx context.Context, c *sockets.Client, room string, text string) (*prompts.Prompt, error) {
+	// get access to games map
+	log.Print("[WritePrompt] - Getting gameService lock")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer log.Print("[WritePrompt] - Releasing gameService lock")
+
+	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
+	game, ok := s.games[room]
+	if !ok {
+		err := fmt.Errorf("game room %s does not exist - failed to write prompt: %w", room, ErrGameNotFound)
+		log.Print(err.Error())
+		return nil, err
+	}
+
+	// get access to game room
+	log.Printf("[WritePrompt] - Getting game %s lock", game.Room)
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+	defer log.Printf("[WritePrompt] - Releasing game %s lock", game.Room)
+
+	if err := abandoned(ctx, "write prompt", room); err != nil {
+		return nil, err
+	}
+
+	client, err := canWritePrompt(game, c)
+	if err != nil {
+		err = fmt.Errorf("failed to write prompt in game %s: %w", room, err)
+		log.Print(err.Error())
+		return nil, err
+	}
+
+	prompt := s.addPrompt(game, client, text)
+
+	return prompt, nil
+}
+
+// UseSavedPrompt puts a prompt from the library into the game's pool in place
+// of one the client would have written.
+func (s *GameService) UseSavedPrompt(ctx context.Context, c *sockets.Client, room string, saved *prompts.SavedPrompt) (*prompts.Prompt, error) {
+	// get access to games map
+	log.Print("[UseSavedPrompt] - Getting gameService lock")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer log.Print("[UseSavedPrompt] - Releasing gameService lock")
+
+	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
+	game, ok := s.games[room]
+	if !ok {
+		err := fmt.Errorf("game room %s does not exist - failed to use saved prompt: %w", room, ErrGameNotFound)
+		log.Print(err.Error())
+		return nil, err
+	}
+
+	// get access to game room
+	log.Printf("[UseSavedPrompt] - Getting game %s lock", game.Room)
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+	defer log.Printf("[UseSavedPrompt] - Releasing game %s lock", game.Room)
+
+	if err := abandoned(ctx, "use saved prompt", room); err != nil {
+		return nil, err
+	}
+
+	client, err := canWritePrompt(game, c)
+	if err != nil {
+		err = fmt.Errorf("failed to use saved prompt in game %s: %w", room, err)
+		log.Print(err.Error())
+		return nil, err
+	}
+
+	log.Printf("Client %s used saved prompt %s in game %s", client.UserID, saved.ID, game.Room)
+	prompt := s.addPrompt(game, client, saved.Text)
+
+	return prompt, nil
+}
+
+// canWritePrompt checks that the client still has prompts left to write.
+// The caller must hold the game lock.
+func canWritePrompt(game *games.Game, c *sockets.Client) (*games.GameClient, error) {
+	client, ok := game.Clients[c]
+	if !ok {
+		return nil, ErrNotInGame
+	}
+	if err := checkPhase(game, messages.MessageTypeWritePrompt); err != nil {
+		return nil, err
+	}
+	if game.Prompts.CountBy(client.UserID) >= game.Prompts.PerPlayer {
+		return nil, ErrPromptQuotaReached
+	}
+	return client, nil
+}
+
+// addPrompt puts a new prompt by the client into the game's pool, telling the
+// room about the submission and moving on to dealing once every player has
+// written their quota. The caller must hold the game lock.
+func (s *GameService) addPrompt(game *games.Game, client *games.GameClient, text string) *prompts.Prompt {
+	prompt := &prompts.Prompt{
+		ID:        uuid.NewString(),
+		Text:      text,
+		AuthorID:  client.UserID,
+		CreatedAt: time.Now(),
+	}
+	game.Prompts.Add(prompt)
+
+	written := game.Prompts.CountBy(client.UserID)
+	log.Printf("Client %s wrote prompt %s in game %s (%d/%d)", client.UserID, prompt.ID, game.Room, written, game.Prompts.PerPlayer)
+
+	// the text stays secret until the prompt is dealt
+	s.broadcast(game, responses.SocketEvent{
+		Event:   responses.EventPromptWritten,
+		Message: fmt.Sprintf("%s wrote a prompt", client.Name),
+		Content: map[string]interface{}{
+			"room":             game.Room,
+			"userID":           client.UserID,
+			"written":          written,
+			"promptsPerPlayer": game.Prompts.PerPlayer,
+		},
+	})
+
+	s.advance(game)
+
+	return prompt
+}
+
+// promptsComplete reports whether every connected player has written their
+// quota of prompts. The caller must hold the game lock.
+func promptsComplete(game *games.Game) bool {
+	connected := 0
+	for _, client := range game.Clients {
+		if !client.Connected {
+			continue
+		}
+		connected++
+		if game.Prompts.CountBy(client.UserID) < game.Prompts.PerPlayer {
+			return false
+		}
+	}
+	return connected > 0
+}
+
+// DealResult is the prompt ReceivePrompt dealt and the round it was dealt for.
+type DealResult struct {
+	Prompt *prompts.Prompt
+	Round  int
+}
+
+func (s *GameService) ReceivePrompt(ctx context.Context, c *sockets.Client, room string) (DealResult, error) {
+	// get access to games map
+	log.Print("[ReceivePrompt] - Getting gameService lock")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer log.Print("[ReceivePrompt] - Releasing gameService lock")
+
+	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
+	game, ok := s.games[room]
+	if !ok {
+		err := fmt.Errorf("game room %s does not exist - failed to receive prompt: %w", room, ErrGameNotFound)
+		log.Print(err.Error())
+		return DealResult{}, err
+	}
+
+	// get access to game room
+	log.Printf("[ReceivePrompt] - Getting game %s lock", game.Room)
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+	defer log.Printf("[ReceivePrompt] - Releasing game %s lock", game.Room)
+
+	if err := abandoned(ctx, "receive prompt", room); err != nil {
+		return DealResult{}, err
+	}
+
+	client, ok := game.Clients[c]
+	if !ok {
+		err := fmt.Errorf("game client does not exist in room %s - failed to receive prompt: %w", room, ErrNotInGame)
+		log.Print(err.Error())
+		return DealResult{}, err
+	}
+
+	if err := checkPhase(game, messages.MessageTypeReceivePrompt); err != nil {
+		err = fmt.Errorf("failed to receive prompt in game %s: %w", room, err)
+		log.Print(err.Error())
+		return DealResult{}, err
+	}
+
+	// once the round is being played only the prompt already held can be fetched again
+	if _, held := game.Prompts.Held[client.UserID]; !held && game.Phase != games.PhaseDealing {
+		err := fmt.Errorf("failed to receive prompt in game %s: %w", room, ErrWaitForRound)
+		log.Print(err.Error())
+		return DealResult{}, err
+	}
+
+	round := game.Prompts.Round
+	prompt, err := s.dealer.Deal(&game.Prompts, client.UserID, playerIDs(game))
+	if err != nil {
+		err = fmt.Errorf("failed to deal prompt to client %s in game %s: %w", client.UserID, room, err)
+		log.Print(err.Error())
+		return DealResult{}, err
+	}
+
+	log.Printf("Dealt prompt %s to client %s in game %s round %d", prompt.ID, client.UserID, room, round)
+
+	s.broadcast(game, responses.SocketEvent{
+		Event:   responses.EventPromptDealt,
+		Message: fmt.Sprintf("%s received a prompt", client.Name),
+		Content: map[string]interface{}{
+			"room":   room,
+			"userID": client.UserID,
+			"round":  round,
+		},
+	})
+	s.advance(game)
+
+	return DealResult{Prompt: prompt, Round: round}, nil
+}
+
+// playerIDs lists the user ids of everyone connected to the game room in
+// seat order, so they come out the same however the clients map is ordered
+// and whatever ids the players were given. The caller must hold the game lock.
+func playerIDs(game *games.Game) []string {
+	seated := make([]*games.GameClient, 0, len(game.Clients))
+	for _, client := range game.Clients {
+		if client.Connected {
+			seated = append(seated, client)
+		}
+	}
+	sort.Slice(seated, func(i, j int) bool {
+		if !seated[i].JoinedAt.Equal(seated[j].JoinedAt) {
+			return seated[i].JoinedAt.Before(seated[j].JoinedAt)
+		}
+		return seated[i].UserID < seated[j].UserID
+	})
+
+	ids := make([]string, len(seated))
+	for i, client := range seated {
+		ids[i] = client.UserID
+	}
+	return ids
+}
+
+func (s *GameService) ResolvePrompt(ctx context.Context, c *sockets.Client, room string, promptID string, resolution prompts.Resolution) error {
+	// get access to games map
+	log.Print("[ResolvePrompt] - Getting gameService lock")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer log.Print("[ResolvePrompt] - Releasing gameService lock")
+
+	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
+	game, ok := s.games[room]
+	if !ok {
+		err := fmt.Errorf("game room %s does not exist - failed to resolve prompt: %w", room, ErrGameNotFound)
+		log.Print(err.Error())
+		return err
+	}
+
+	// get access to game room
+	log.Printf("[ResolvePrompt] - Getting game %s lock", game.Room)
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+	defer log.Printf("[ResolvePrompt] - Releasing game %s lock", game.Room)
+
+	if err := abandoned(ctx, "resolve prompt", room); err != nil {
+		return err
+	}
+
+	client, ok := game.Clients[c]
+	if !ok {
+		err := fmt.Errorf("game client does not exist in room %s - failed to resolve prompt: %w", room, ErrNotInGame)
+		log.Print(err.Error())
+		return err
+	}
+
+	if err := checkPhase(game, messageTypeFor(resolution)); err != nil {
+		err = fmt.Errorf("failed to resolve prompt in game %s: %w", room, err)
+		log.Print(err.Error())
+		return err
+	}
+
+	// a player holding nothing must not match an empty prompt id
+	held, ok := game.Prompts.Held[client.UserID]
+	prompt, exists := game.Prompts.Prompts[promptID]
+	if !ok || held != promptID || !exists {
+		err := fmt.Errorf("client %s does not hold prompt %s in game %s: %w", client.UserID, promptID, room, ErrPromptNotHeld)
+		log.Print(err.Error())
+		return err
+	}
+
+	outcome := prompts.Outcome{
+		PromptID:   promptID,
+		PlayerID:   client.UserID,
+		Resolution: resolution,
+		Round:      game.Prompts.LastRound[client.UserID],
+		ResolvedAt: time.Now(),
+	}
+	game.Prompts.Outcomes = append(game.Prompts.Outcomes, outcome)
+	delete(game.Prompts.Held, client.UserID)
+
+	score, ok := game.Scores[client.UserID]
+	if !ok {
+		score = &games.Score{}
+		game.Scores[client.UserID] = score
+	}
+	switch resolution {
+	case prompts.Performed:
+		score.Performed++
+	case prompts.Drank:
+		score.Drank++
+	}
+
+	message := fmt.Sprintf("%s performed \"%s\"", client.Name, prompt.Text)
+	if resolution == prompts.Drank {
+		message = fmt.Sprintf("%s drank instead of \"%s\"", client.Name, prompt.Text)
+	}
+	log.Printf("Client %s %s prompt %s in game %s", client.UserID, resolution, promptID, room)
+
+	s.broadcast(game, responses.SocketEvent{
+		Event:   responses.EventPromptResolved,
+		Message: message,
+		Content: map[string]interface{}{
+			"room":       room,
+			"userID":     client.UserID,
+			"promptID":   promptID,
+			"prompt":     prompt.Text,
+			"resolution": resolution,
+			"round":      outcome.Round,
+			"performed":  score.Performed,
+			"drank":      score.Drank,
+		},
+	})
+	s.advance(game)
+
+	return nil
+}
+
+func messageTypeFor(resolution prompts.Resolution) messages.MessageType {
+	if resolution == prompts.Drank {
+		return messages.MessageTypeDrinkForPrompt
+	}
+	return messages.MessageTypePerformPrompt
+}
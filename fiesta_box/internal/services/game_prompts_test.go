@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
+)
+
+// newPromptGame seats ann as master and bob in the given phase, with an empty
+// prompt pool.
+func newPromptGame(phase games.Phase) (*GameService, *games.Game, *sockets.Client, *sockets.Client) {
+	ann, bob := &sockets.Client{}, &sockets.Client{}
+	game := newTestGame("ROOM", time.Now())
+	game.Phase = phase
+	game.MasterID = "ann"
+	game.Prompts = prompts.NewPool()
+	game.Scores = map[string]*games.Score{}
+	game.Clients[ann] = &games.GameClient{Room: "ROOM", Client: ann, UserID: "ann", Name: "Ann", Connected: true}
+	game.Clients[bob] = &games.GameClient{Room: "ROOM", Client: bob, UserID: "bob", Name: "Bob", Connected: true}
+
+	s := NewGameService()
+	s.games = map[string]*games.Game{"ROOM": game}
+	return s, game, ann, bob
+}
+
+func TestConfigurePromptCount(t *testing.T) {
+	s, game, ann, bob := newPromptGame(games.PhaseLobby)
+	ctx := context.Background()
+
+	if err := s.ConfigurePromptCount(ctx, bob, "ROOM", 2); !errors.Is(err, ErrNotMaster) {
+		t.Fatalf("expected ErrNotMaster, got %v", err)
+	}
+	for _, count := range []int{prompts.MinPerPlayer - 1, prompts.MaxPerPlayer + 1} {
+		if err := s.ConfigurePromptCount(ctx, ann, "ROOM", count); !errors.Is(err, ErrInvalidPromptCount) {
+			t.Fatalf("expected ErrInvalidPromptCount for %d, got %v", count, err)
+		}
+	}
+	if game.Prompts.PerPlayer != prompts.DefaultPerPlayer {
+		t.Fatalf("expected the count to be unchanged, got %d", game.Prompts.PerPlayer)
+	}
+
+	if err := s.ConfigurePromptCount(ctx, ann, "ROOM", 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	event := <-game.Broadcast
+	if game.Prompts.PerPlayer != 2 || event.Event != responses.EventPromptCountChanged {
+		t.Fatalf("expected 2 prompts each and the room told, got %d and %+v", game.Prompts.PerPlayer, event)
+	}
+
+	game.Phase = games.PhaseWritingPrompts
+	if err := s.ConfigurePromptCount(ctx, ann, "ROOM", 3); !errors.Is(err, ErrWrongPhase) {
+		t.Fatalf("expected the count to be fixed once writing starts, got %v", err)
+	}
+}
+
+func TestWritePromptQuota(t *testing.T) {
+	s, game, ann, _ := newPromptGame(games.PhaseWritingPrompts)
+	game.Prompts.PerPlayer = 2
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		prompt, err := s.WritePrompt(ctx, ann, "ROOM", "Sing a song")
+		if err != nil || prompt.AuthorID != "ann" || prompt.Text != "Sing a song" {
+			t.Fatalf("expected ann's prompt, got %+v %v", prompt, err)
+		}
+	}
+	if _, err := s.WritePrompt(ctx, ann, "ROOM", "One more"); !errors.Is(err, ErrPromptQuotaReached) {
+		t.Fatalf("expected ErrPromptQuotaReached, got %v", err)
+	}
+	if count := game.Prompts.CountBy("ann"); count != 2 {
+		t.Fatalf("expected 2 prompts by ann, got %d", count)
+	}
+}
+
+func TestNormalizePrompt(t *testing.T) {
+	if text, err := NormalizePrompt("  Sing a song "); err != nil || text != "Sing a song" {
+		t.Fatalf("expected the prompt to be trimmed, got %q %v", text, err)
+	}
+	for _, text := range []string{"", "   ", strings.Repeat("a", prompts.MaxTextLength+1)} {
+		if _, err := NormalizePrompt(text); !errors.Is(err, ErrInvalidPrompt) {
+			t.Errorf("%q: expected ErrInvalidPrompt, got %v", text, err)
+		}
+	}
+}
+
+func TestPromptsReadyOnceEveryoneHasWritten(t *testing.T) {
+	s, game, ann, bob := newPromptGame(games.PhaseWritingPrompts)
+	game.Prompts.PerPlayer = 1
+	// a player who lost their connection is not waited for
+	game.Clients[&sockets.Client{}] = &games.GameClient{Room: "ROOM", UserID: "cat", Name: "Cat"}
+	ctx := context.Background()
+
+	if _, err := s.WritePrompt(ctx, ann, "ROOM", "Sing a song"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if game.Prompts.Ready || game.Phase != games.PhaseWritingPrompts {
+		t.Fatalf("expected to wait for bob, got ready=%v in the %s phase", game.Prompts.Ready, game.Phase)
+	}
+
+	if _, err := s.WritePrompt(ctx, bob, "ROOM", "Dance"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !game.Prompts.Ready || game.Phase != games.PhaseDealing {
+		t.Fatalf("expected the pool to be ready for dealing, got ready=%v in the %s phase", game.Prompts.Ready, game.Phase)
+	}
+
+	var ready *responses.SocketEvent
+	for len(game.Broadcast) > 0 {
+		if event := <-game.Broadcast; event.Event == responses.EventPhaseChanged {
+			ready = &event
+		}
+	}
+	if ready == nil {
+		t.Fatal("expected the room to be told the prompts are ready")
+	}
+	if content, _ := ready.Content.(map[string]interface{}); content["promptsReady"] != true {
+		t.Fatalf("expected promptsReady in %+v", ready.Content)
+	}
+}
+
+func TestResolvePromptWhileHoldingNothing(t *testing.T) {
+	c := &sockets.Client{}
+	game := newTestGame("ROOM", time.Now())
+	game.Phase = games.PhasePlaying
+	game.Prompts = prompts.NewPool()
+	game.Clients[c] = &games.GameClient{Room: "ROOM", UserID: "user-1", Connected: true}
+
+	s := NewGameService()
+	s.games = map[string]*games.Game{"ROOM": game}
+
+	for _, promptID := range []string{"", "missing"} {
+		err := s.ResolvePrompt(context.Background(), c, "room", promptID, prompts.Performed)
+		if !errors.Is(err, ErrPromptNotHeld) {
+			t.Fatalf("expected ErrPromptNotHeld for prompt %q, got %v", promptID, err)
+		}
+	}
+}
+
+func TestResolvePromptTalliesScores(t *testing.T) {
+	s, game, ann, bob := newPromptGame(games.PhasePlaying)
+	game.Prompts.Add(&prompts.Prompt{ID: "ann-0", Text: "Sing a song", AuthorID: "ann"})
+	game.Prompts.Add(&prompts.Prompt{ID: "bob-0", Text: "Dance", AuthorID: "bob"})
+	game.Prompts.DealtTo = map[string]string{"bob-0": "ann", "ann-0": "bob"}
+	game.Prompts.Held = map[string]string{"ann": "bob-0", "bob": "ann-0"}
+	game.Prompts.LastRound = map[string]int{"ann": 1, "bob": 1}
+	ctx := context.Background()
+
+	if err := s.ResolvePrompt(ctx, ann, "ROOM", "ann-0", prompts.Performed); !errors.Is(err, ErrPromptNotHeld) {
+		t.Fatalf("expected ann not to resolve bob's prompt, got %v", err)
+	}
+	if err := s.ResolvePrompt(ctx, ann, "ROOM", "bob-0", prompts.Performed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.ResolvePrompt(ctx, bob, "ROOM", "ann-0", prompts.Drank); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if score := game.Scores["ann"]; score == nil || score.Performed != 1 || score.Drank != 0 {
+		t.Fatalf("expected ann to have performed once, got %+v", score)
+	}
+	if score := game.Scores["bob"]; score == nil || score.Performed != 0 || score.Drank != 1 {
+		t.Fatalf("expected bob to have drunk once, got %+v", score)
+	}
+	if len(game.Prompts.Held) != 0 || len(game.Prompts.Outcomes) != 2 {
+		t.Fatalf("expected both prompts to be resolved, got held %v and outcomes %+v", game.Prompts.Held, game.Prompts.Outcomes)
+	}
+
+	resolved := []map[string]interface{}{}
+	for len(game.Broadcast) > 0 {
+		if event := <-game.Broadcast; event.Event == responses.EventPromptResolved {
+			resolved = append(resolved, event.Content.(map[string]interface{}))
+		}
+	}
+	if len(resolved) != 2 {
+		t.Fatalf("expected two resolutions to be broadcast, got %v", resolved)
+	}
+	if first := resolved[0]; first["userID"] != "ann" || first["promptID"] != "bob-0" || first["prompt"] != "Dance" ||
+		first["resolution"] != prompts.Performed || first["performed"] != 1 || first["drank"] != 0 {
+		t.Fatalf("unexpected broadcast for ann %v", first)
+	}
+	if second := resolved[1]; second["userID"] != "bob" || second["resolution"] != prompts.Drank || second["drank"] != 1 {
+		t.Fatalf("unexpected broadcast for bob %v", second)
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/responses"
+)
+
+// ReaperConfig controls how often abandoned game rooms are cleaned up.
+type ReaperConfig struct {
+	// Interval is how often rooms are checked.
+	Interval time.Duration
+	// EmptyTTL is how long a room with nobody connected is kept.
+	EmptyTTL time.Duration
+	// IdleTTL is how long a room with no activity at all is kept.
+	IdleTTL time.Duration
+}
+
+var DefaultReaperConfig = ReaperConfig{
+	Interval: time.Minute,
+	EmptyTTL: 5 * time.Minute,
+	IdleTTL:  time.Hour,
+}
+
+// StartReaper deletes game rooms that have been empty or idle for too long in
+// the background. Calling the returned function stops it.
+func (s *GameService) StartReaper(config ReaperConfig) (stop func()) {
+	ticker := time.NewTicker(config.Interval)
+	quit := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				s.reap(config, time.Now())
+			case <-quit:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+	}
+}
+
+// reap deletes every game room that is past its TTL at now.
+func (s *GameService) reap(config ReaperConfig, now time.Time) {
+	// get access to games map
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, game := range s.games {
+		s.reapGame(game, config, now)
+	}
+}
+
+func (s *GameService) reapGame(game *games.Game, config ReaperConfig, now time.Time) {
+	// get access to game room
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+
+	idle := now.Sub(game.LastActivity)
+
+	switch {
+	case connectedCount(game) == 0 && idle > config.EmptyTTL:
+		log.Printf("Deleting game room %s. No players connected for %s.", game.Room, idle.Round(time.Second))
+	case idle > config.IdleTTL:
+		log.Printf("Deleting game room %s. No activity for %s.", game.Room, idle.Round(time.Second))
+		s.broadcast(game, responses.SocketEvent{
+			Event:   responses.EventRoomClosed,
+			Message: "Game room closed due to inactivity",
+			Content: map[string]interface{}{
+				"room":   game.Room,
+				"closed": true,
+			},
+		})
+	default:
+		return
+	}
+
+	s.deleteGame(game)
+}
+
+// deleteGame removes the game room and stops its hub once any queued
+// broadcasts are sent. The caller must hold the gameService lock and the
+// game lock, and must not broadcast to the game afterwards.
+func (s *GameService) deleteGame(game *games.Game) {
+	delete(s.games, game.Room)
+	close(game.Broadcast)
+}
+
+// connectedCount counts the connected clients in the game room.
+// The caller must hold the game lock.
+func connectedCount(game *games.Game) int {
+	count := 0
+	for _, client := range game.Clients {
+		if client.Connected {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
+)
+
+func newTestGame(room string, lastActivity time.Time, connected ...bool) *games.Game {
+	game := &games.Game{
+		Clients:      map[*sockets.Client]*games.GameClient{},
+		Broadcast:    make(chan responses.SocketEvent, broadcastBuffer),
+		Room:         room,
+		LastActivity: lastActivity,
+	}
+	for _, c := range connected {
+		game.Clients[&sockets.Client{}] = &games.GameClient{Room: room, Connected: c}
+	}
+	return game
+}
+
+func TestReapDeletesExpiredRooms(t *testing.T) {
+	now := time.Now()
+	config := ReaperConfig{EmptyTTL: time.Minute, IdleTTL: time.Hour}
+
+	s := NewGameService()
+	s.games = map[string]*games.Game{
+		"fresh-empty":  newTestGame("fresh-empty", now.Add(-30*time.Second)),
+		"stale-empty":  newTestGame("stale-empty", now.Add(-2*time.Minute), false, false),
+		"stale-active": newTestGame("stale-active", now.Add(-2*time.Minute), true),
+		"idle":         newTestGame("idle", now.Add(-2*time.Hour), true),
+	}
+
+	s.reap(config, now)
+
+	for room, kept := range map[string]bool{
+		"fresh-empty":  true,
+		"stale-empty":  false,
+		"stale-active": true,
+		"idle":         false,
+	} {
+		if _, ok := s.games[room]; ok != kept {
+			t.Errorf("room %s: expected kept=%v, got %v", room, kept, ok)
+		}
+	}
+}
+
+func TestReapStopsHubOfDeletedRoom(t *testing.T) {
+	now := time.Now()
+	game := newTestGame("idle", now.Add(-2*time.Hour), true)
+
+	s := NewGameService()
+	s.games = map[string]*games.Game{"idle": game}
+	s.reap(DefaultReaperConfig, now)
+
+	// the closing notice is still queued for the hub, then the channel is closed
+	if _, ok := <-game.Broadcast; !ok {
+		t.Fatal("expected the closing notice before the broadcast channel closed")
+	}
+	if _, ok := <-game.Broadcast; ok {
+		t.Fatal("expected the broadcast channel to be closed")
+	}
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/messages"
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
+)
+
+var ErrInvalidRoundCount = fmt.Errorf("round count must be between 0 and %d", prompts.MaxPerPlayer)
+
+// ResultsRecorder keeps the results of a finished game somewhere they outlive
+// the room, such as the history of the players' accounts.
+type ResultsRecorder func(ctx context.Context, results *games.Results) error
+
+// recordTimeout bounds how long recording the results of one game may take.
+const recordTimeout = 10 * time.Second
+
+// RecordResultsWith has the results of every game that finishes from now on
+// passed to record. It must be called before any game is played.
+func (s *GameService) RecordResultsWith(record ResultsRecorder) {
+	s.recorder = record
+}
+
+func (s *GameService) ConfigureRoundCount(ctx context.Context, c *sockets.Client, room string, rounds int) error {
+	// get access to games map
+	log.Print("[ConfigureRoundCount] - Getting gameService lock")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer log.Print("[ConfigureRoundCount] - Releasing gameService lock")
+
+	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
+	game, ok := s.games[room]
+	if !ok {
+		err := fmt.Errorf("game room %s does not exist - failed to configure round count: %w", room, ErrGameNotFound)
+		log.Print(err.Error())
+		return err
+	}
+
+	// get access to game room
+	log.Printf("[ConfigureRoundCount] - Getting game %s lock", game.Room)
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+	defer log.Printf("[ConfigureRoundCount] - Releasing game %s lock", game.Room)
+
+	if err := abandoned(ctx, "configure round count", room); err != nil {
+		return err
+	}
+
+	if err := canConfigureRoundCount(game, c, rounds); err != nil {
+		err = fmt.Errorf("failed to configure round count of game %s: %w", room, err)
+		log.Print(err.Error())
+		return err
+	}
+
+	game.RoundLimit = rounds
+
+	message := fmt.Sprintf("The game will last %d rounds", rounds)
+	if rounds == 0 {
+		message = "The game will last until the prompts run out"
+	}
+	log.Printf("Game %s round count configured to %d", room, rounds)
+
+	s.broadcast(game, responses.SocketEvent{
+		Event:   responses.EventRoundCountChanged,
+		Message: message,
+		Content: map[string]interface{}{
+			"room":   room,
+			"rounds": rounds,
+		},
+	})
+
+	return nil
+}
+
+// canConfigureRoundCount checks that the client may set the round count.
+// The caller must hold the game lock.
+func canConfigureRoundCount(game *games.Game, c *sockets.Client, rounds int) error {
+	client, ok := game.Clients[c]
+	if !ok {
+		return ErrNotInGame
+	}
+	if client.UserID != game.MasterID {
+		return ErrNotMaster
+	}
+	if err := checkPhase(game, messages.MessageTypeConfigureRoundCount); err != nil {
+		return err
+	}
+	if rounds < 0 || rounds > prompts.MaxPerPlayer {
+		return ErrInvalidRoundCount
+	}
+	return nil
+}
+
+// Results returns the recap of a finished game.
+func (s *GameService) Results(ctx context.Context, room string) (*games.Results, error) {
+	// get access to games map
+	log.Print("[Results] - Getting gameService lock")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer log.Print("[Results] - Releasing gameService lock")
+
+	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
+	game, ok := s.games[room]
+	if !ok {
+		err := fmt.Errorf("game room %s does not exist - failed to get results: %w", room, ErrGameNotFound)
+		log.Print(err.Error())
+		return nil, err
+	}
+
+	// get access to game room
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+
+	if err := abandoned(ctx, "get results", room); err != nil {
+		return nil, err
+	}
+
+	if err := checkPhase(game, messages.MessageTypeGetResults); err != nil {
+		err = fmt.Errorf("failed to get results of game %s: %w", room, err)
+		log.Print(err.Error())
+		return nil, err
+	}
+
+	return game.Results, nil
+}
+
+// roundsDone reports whether the game has played its configured number of
+// rounds. The caller must hold the game lock.
+func roundsDone(game *games.Game) bool {
+	return game.RoundLimit > 0 && game.Prompts.Round >= game.RoundLimit
+}
+
+// finish computes the results of the game, shares them with the room and
+// completes the game. The caller must hold the game lock.
+func (s *GameService) finish(game *games.Game) {
+	if err := s.setPhase(game, games.PhaseResults); err != nil {
+		return
+	}
+
+	game.Results = computeResults(game)
+
+	s.broadcast(game, responses.SocketEvent{
+		Event:   responses.EventResultsReady,
+		Message: fmt.Sprintf("Results are in for game %s", game.Room),
+		Content: game.Results,
+	})
+
+	if err := s.setPhase(game, games.PhaseCompleted); err != nil {
+		return
+	}
+
+	if s.recorder != nil {
+		// the results are never changed once computed, so they can be shared
+		// with the recorder after the game lock is released
+		go s.record(game.Results)
+	}
+}
+
+// record hands the results to the recorder, which may be slow or fail while
+// the database is down. Either way the game is already over.
+func (s *GameService) record(results *games.Results) {
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+
+	if err := s.recorder(ctx, results); err != nil {
+		log.Printf("Could not record the results of game %s: %v", results.Room, err)
+	}
+}
+
+// computeResults tallies every player's outcomes, picks the MVP and finds the
+// most skipped author. The caller must hold the game lock.
+func computeResults(game *games.Game) *games.Results {
+	results := &games.Results{
+		GameID:      game.ID,
+		Room:        game.Room,
+		Players:     []games.PlayerResult{},
+		CompletedAt: time.Now(),
+	}
+
+	joined := map[string]time.Time{}
+	for _, client := range game.Clients {
+		score := games.Score{}
+		if s, ok := game.Scores[client.UserID]; ok {
+			score = *s
+		}
+		joined[client.UserID] = client.JoinedAt
+		results.Players = append(results.Players, games.PlayerResult{
+			UserID:     client.UserID,
+			Registered: client.Registered,
+			Name:       client.Name,
+			Performed:  score.Performed,
+			Drank:      score.Drank,
+		})
+	}
+
+	// best performers first; fewer drinks and then seniority break ties
+	sort.Slice(results.Players, func(i, j int) bool {
+		a, b := results.Players[i], results.Players[j]
+		if a.Performed != b.Performed {
+			return a.Performed > b.Performed
+		}
+		if a.Drank != b.Drank {
+			return a.Drank < b.Drank
+		}
+		return joined[a.UserID].Before(joined[b.UserID])
+	})
+
+	if len(results.Players) > 0 && results.Players[0].Performed > 0 {
+		mvp := results.Players[0]
+		results.MVP = &mvp
+	}
+
+	for _, outcome := range game.Prompts.Outcomes {
+		if outcome.Round > results.Rounds {
+			results.Rounds = outcome.Round
+		}
+	}
+
+	results.MostSkippedAuthor = mostSkippedAuthor(game)
+
+	return results
+}
+
+// mostSkippedAuthor finds the author whose prompts were drunk for the most
+// times. Ties go to the author who reached that count first.
+func mostSkippedAuthor(game *games.Game) *games.AuthorResult {
+	skipped := map[string]int{}
+	var result *games.AuthorResult
+	for _, outcome := range game.Prompts.Outcomes {
+		if outcome.Resolution != prompts.Drank {
+			continue
+		}
+		author := game.Prompts.Prompts[outcome.PromptID].AuthorID
+		skipped[author]++
+		if result == nil || skipped[author] > result.Skipped {
+			result = &games.AuthorResult{AuthorID: author, Skipped: skipped[author]}
+		}
+	}
+
+	// the author may have left the game since
+	if result != nil {
+		if client := findClient(game, result.AuthorID); client != nil {
+			result.Name = client.Name
+		}
+	}
+	return result
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
+)
+
+// newDealingGame seats the players in order, each having written perPlayer
+// prompts, with dealing about to start.
+func newDealingGame(players []string, perPlayer int) (*GameService, *games.Game, map[string]*sockets.Client) {
+	game := newTestGame("ROOM", time.Now())
+	game.Phase = games.PhaseDealing
+	game.Prompts = *newTestPool(players, perPlayer)
+	game.Scores = map[string]*games.Score{}
+
+	conns := map[string]*sockets.Client{}
+	joined := time.Now()
+	for i, player := range players {
+		c := &sockets.Client{}
+		conns[player] = c
+		game.Clients[c] = &games.GameClient{
+			Room:      "ROOM",
+			Client:    c,
+			UserID:    player,
+			Name:      player,
+			Connected: true,
+			JoinedAt:  joined.Add(time.Duration(i) * time.Second),
+		}
+	}
+
+	s := NewGameServiceWithDealer(DefaultGameConfig, NewDealer(1))
+	s.games = map[string]*games.Game{"ROOM": game}
+	return s, game, conns
+}
+
+// playOut deals and resolves rounds until nobody can be dealt any more. The
+// players in drinkers drink for every prompt, everyone else performs.
+func playOut(t *testing.T, s *GameService, players []string, conns map[string]*sockets.Client, drinkers ...string) {
+	t.Helper()
+	ctx := context.Background()
+
+	resolution := map[string]prompts.Resolution{}
+	for _, player := range players {
+		resolution[player] = prompts.Performed
+	}
+	for _, player := range drinkers {
+		resolution[player] = prompts.Drank
+	}
+
+	for {
+		dealt := map[string]string{}
+		for _, player := range players {
+			if deal, err := s.ReceivePrompt(ctx, conns[player], "ROOM"); err == nil {
+				dealt[player] = deal.Prompt.ID
+			}
+		}
+		if len(dealt) == 0 {
+			return
+		}
+		for _, player := range players {
+			if promptID, ok := dealt[player]; ok {
+				if err := s.ResolvePrompt(ctx, conns[player], "ROOM", promptID, resolution[player]); err != nil {
+					t.Fatalf("could not resolve %s for %s: %v", promptID, player, err)
+				}
+			}
+		}
+	}
+}
+
+func TestGameEndsAfterRoundLimit(t *testing.T) {
+	players := []string{"ann", "bob", "cat"}
+	s, game, conns := newDealingGame(players, 3)
+	game.RoundLimit = 1
+
+	playOut(t, s, players, conns)
+
+	if game.Phase != games.PhaseCompleted || game.Status != games.Completed {
+		t.Fatalf("expected the game to be completed, it is in the %s phase", game.Phase)
+	}
+	if game.Results == nil || game.Results.Rounds != 1 {
+		t.Fatalf("expected results for one round, got %+v", game.Results)
+	}
+	if undealt := game.Prompts.Undealt(); undealt != 6 {
+		t.Fatalf("expected prompts left over after one round, got %d", undealt)
+	}
+}
+
+func TestGameEndsWhenPoolRunsOut(t *testing.T) {
+	players := []string{"ann", "bob", "cat"}
+	s, game, conns := newDealingGame(players, 2)
+
+	playOut(t, s, players, conns)
+
+	if game.Phase != games.PhaseCompleted {
+		t.Fatalf("expected the game to be completed, it is in the %s phase", game.Phase)
+	}
+	if game.Prompts.Undealt() != 0 || len(game.Prompts.Outcomes) != 6 {
+		t.Fatalf("expected every prompt to be played, got %d undealt and %d outcomes", game.Prompts.Undealt(), len(game.Prompts.Outcomes))
+	}
+	if game.Results == nil || game.Results.Rounds != 2 {
+		t.Fatalf("expected results for two rounds, got %+v", game.Results)
+	}
+}
+
+func TestResultsSummariseEveryPlayer(t *testing.T) {
+	players := []string{"ann", "bob", "cat"}
+	s, game, conns := newDealingGame(players, 2)
+	game.ID = "game-1"
+
+	playOut(t, s, players, conns, "bob")
+
+	results, err := s.Results(context.Background(), "room")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results.GameID != "game-1" || results.Room != "ROOM" || results.CompletedAt.IsZero() {
+		t.Fatalf("expected results for game-1, got %+v", results)
+	}
+
+	// ann and cat both performed twice; ann sat down first
+	expected := []games.PlayerResult{
+		{UserID: "ann", Name: "ann", Performed: 2},
+		{UserID: "cat", Name: "cat", Performed: 2},
+		{UserID: "bob", Name: "bob", Drank: 2},
+	}
+	if len(results.Players) != len(expected) {
+		t.Fatalf("expected %d players, got %+v", len(expected), results.Players)
+	}
+	for i, player := range expected {
+		if results.Players[i] != player {
+			t.Errorf("expected player %d to be %+v, got %+v", i, player, results.Players[i])
+		}
+	}
+	if results.MVP == nil || results.MVP.UserID != "ann" {
+		t.Fatalf("expected ann to be MVP, got %+v", results.MVP)
+	}
+	if results.MostSkippedAuthor == nil || results.MostSkippedAuthor.AuthorID == "bob" {
+		t.Fatalf("expected one of the prompts bob drank for to be reported, got %+v", results.MostSkippedAuthor)
+	}
+
+	shared := false
+	for len(game.Broadcast) > 0 {
+		if event := <-game.Broadcast; event.Event == responses.EventResultsReady && event.Content == results {
+			shared = true
+		}
+	}
+	if !shared {
+		t.Fatal("expected the results to be broadcast to the room")
+	}
+}
+
+func TestMostSkippedAuthorCountsEveryDrink(t *testing.T) {
+	game := newTestGame("ROOM", time.Now())
+	game.Clients[&sockets.Client{}] = &games.GameClient{Room: "ROOM", UserID: "ann", Name: "Ann", Connected: true}
+	game.Prompts = prompts.NewPool()
+	for _, prompt := range []*prompts.Prompt{
+		{ID: "ann-0", AuthorID: "ann"},
+		{ID: "ann-1", AuthorID: "ann"},
+		{ID: "bob-0", AuthorID: "bob"},
+	} {
+		game.Prompts.Add(prompt)
+	}
+	game.Prompts.Outcomes = []prompts.Outcome{
+		{PromptID: "bob-0", PlayerID: "ann", Resolution: prompts.Drank},
+		{PromptID: "ann-0", PlayerID: "bob", Resolution: prompts.Drank},
+		{PromptID: "ann-1", PlayerID: "cat", Resolution: prompts.Drank},
+	}
+
+	result := mostSkippedAuthor(game)
+	if result == nil || result.AuthorID != "ann" || result.Name != "Ann" || result.Skipped != 2 {
+		t.Fatalf("expected Ann skipped twice, got %+v", result)
+	}
+}
@@ -1,25 +1,62 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/gorilla/websocket"
 
 	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/messages"
+	"fiesta_box/internal/models/prompts"
 	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
 )
 
+var (
+	ErrGameNotFound = errors.New("game room does not exist")
+	ErrNotInGame = errors.New("client is not in this game room")
+	ErrNotMaster = errors.New("only the game master can do this")
+	ErrNotEnoughPlayers = fmt.Errorf("at least %d players are needed to start", games.MinPlayers)
+	ErrTooManyPlayers = errors.New("there are too many players to start")
+	ErrPlayerNotFound = errors.New("no player with that id is in this game room")
+	ErrPlayerDisconnected = errors.New("player is disconnected")
+)
+
+// GameServiceInterface is the part of the game service that message handlers
+// use. Every method runs synchronously and gives up without changing anything
+// if ctx is done by the time it gets hold of the game.
 type GameServiceInterface interface {
-	NewGame(c *websocket.Conn, room string) games.Game
+	NewGame(ctx context.Context, c *sockets.Client, name string, userID string) (*games.GameClient, error)
+	AddToGame(ctx context.Context, c *sockets.Client, room string, name string, userID string) (*games.GameClient, error)
+	RemoveFromGame(ctx context.Context, c *sockets.Client, room string) error
+	ChangePlayerName(ctx context.Context, c *sockets.Client, room string, name string) error
+	StartGame(ctx context.Context, c *sockets.Client, room string) error
+	TransferMaster(ctx context.Context, c *sockets.Client, room string, userID string) error
+	ConfigurePromptCount(ctx context.Context, c *sockets.Client, room string, count int) error
+	ConfigureRoundCount(ctx context.Context, c *sockets.Client, room string, rounds int) error
+	WritePrompt(ctx context.Context, c *sockets.Client, room string, text string) (*prompts.Prompt, error)
+	UseSavedPrompt(ctx context.Context, c *sockets.Client, room string, saved *prompts.SavedPrompt) (*prompts.Prompt, error)
+	ReceivePrompt(ctx context.Context, c *sockets.Client, room string) (DealResult, error)
+	ResolvePrompt(ctx context.Context, c *sockets.Client, room string, promptID string, resolution prompts.Resolution) error
+	ResumeSession(ctx context.Context, c *sockets.Client, room string, token string) (map[string]interface{}, error)
+	Results(ctx context.Context, room string) (*games.Results, error)
 }
 
+var _ GameServiceInterface = (*GameService)(nil)
+
 type GameService struct{
 	games map[string]*games.Game
 	mutex sync.Mutex // mutex around games map
-} 
+	dealer *Dealer
+	config GameConfig
+	// recorder keeps the results of finished games, if set
+	recorder ResultsRecorder
+}
 
 type GameServiceState struct {
 	Games int `json:"games"`
@@ -27,67 +64,135 @@ type GameServiceState struct {
 
 }
 
+// GameConfig holds the settings the game service runs its rooms with.
+type GameConfig struct {
+	// MaxPlayers caps how many players can sit in a room.
+	MaxPlayers int
+	// LateJoin lets players join a game that has already started.
+	LateJoin bool
+	// ResumeGrace is how long a disconnected player keeps their seat.
+	ResumeGrace time.Duration
+}
+
+var DefaultGameConfig = GameConfig{
+	MaxPlayers: games.MaxPlayers,
+	LateJoin: false,
+	ResumeGrace: 2 * time.Minute,
+}
+
 func NewGameService() *GameService {
+	return NewGameServiceWithConfig(DefaultGameConfig)
+}
+
+func NewGameServiceWithConfig(config GameConfig) *GameService {
+	return NewGameServiceWithDealer(config, newTimeSeededDealer())
+}
+
+// NewGameServiceWithDealer builds the service with the dealer it deals every
+// game's prompts with, such as NewDealer(seed) to replay the same deals.
+func NewGameServiceWithDealer(config GameConfig, dealer *Dealer) *GameService {
 	return &GameService{
 		games: map[string]*games.Game{},
 		mutex: sync.Mutex{},
+		dealer: dealer,
+		config: config,
 	}
 }
 
-func (s *GameService) CreateGameClient(c *websocket.Conn, room string) *games.GameClient {
+// CreateGameClient seats the connection as the user, or as a guest with an id
+// of its own when userID is empty.
+func (s *GameService) CreateGameClient(c *sockets.Client, room string, name string, userID string) *games.GameClient {
+	registered := userID != ""
+	if !registered {
+		userID = uuid.NewString()
+	}
 	client := games.GameClient{
 		Room: room,
 		Client: c,
-		UserID: uuid.NewString(),
+		UserID: userID,
+		Registered: registered,
+		Name: name,
 		Connected: true,
+		JoinedAt: time.Now(),
+		ResumeToken: newResumeToken(),
 	}
 	log.Printf("Created game client %s", client.UserID)
 	return &client
 }
 
+// abandoned reports whether the request behind ctx was cancelled or timed out
+// while it waited for the locks, in which case it must not touch the game.
+// An empty room is for actions that come before there is a game to name.
+func abandoned(ctx context.Context, action string, room string) error {
+	if err := ctx.Err(); err != nil {
+		if room == "" {
+			err = fmt.Errorf("gave up trying to %s: %w", action, err)
+		} else {
+			err = fmt.Errorf("gave up trying to %s in game %s: %w", action, room, err)
+		}
+		log.Print(err.Error())
+		return err
+	}
+	return nil
+}
 
-func (s *GameService) NewGame(c *websocket.Conn, done chan *games.Game) (*games.Game, error) {
+func (s *GameService) NewGame(ctx context.Context, c *sockets.Client, name string, userID string) (*games.GameClient, error) {
 	// get access to games map
 	log.Print("[NewGame] - Getting gameService lock")
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	defer log.Print("[NewGame] - Releasing gameService lock")
 
-	room := uuid.NewString()
-
-	// check if room exists, fail if it does
-	if _, ok := s.games[room]; ok {
-		var g *games.Game
-		return g, fmt.Errorf("game room %s already exists", room)
+	if err := abandoned(ctx, "create game", ""); err != nil {
+		return nil, err
 	}
 
-	// create game client for this websocket connection
-	client := s.CreateGameClient(c, room)
-
-	// add game client to the game room's client map
-	clients := map[*websocket.Conn]*games.GameClient{
-		c: client,
+	// pick a room code that isn't taken yet
+	room, err := s.newRoomCode()
+	if err != nil {
+		err = fmt.Errorf("failed to create game room: %w", err)
+		log.Print(err.Error())
+		return nil, err
 	}
 
 	// create game room
 	game := games.Game{
-		Clients: clients,
-		Broadcast: make(chan responses.SocketResponse),
+		ID: uuid.NewString(),
+		Clients: map[*sockets.Client]*games.GameClient{},
+		Broadcast: make(chan responses.SocketEvent, broadcastBuffer),
 		Status: games.NotStarted,
+		Phase: games.PhaseLobby,
 		Mutex: sync.Mutex{},
 		Room: room,
+		Prompts: prompts.NewPool(),
+		Scores: map[string]*games.Score{},
+		LastActivity: time.Now(),
+		MaxPlayers: s.config.MaxPlayers,
+		LateJoin: s.config.LateJoin,
 	}
+
+	if name == "" {
+		name = defaultName(&game)
+	}
+
+	// create game client for this websocket connection and make it the master
+	client := s.CreateGameClient(c, room, name, userID)
+	game.Clients[c] = client
+	game.MasterID = client.UserID
+
 	// add game room to game service map
 	s.games[room] = &game
-	log.Printf("Created game room %s", game.Room)
+	log.Printf("Created game room %s (%s)", game.Room, game.ID)
 
-	done <- &game
+	go s.runHub(&game)
 
-	// return the created game room
-	return &game, nil
+	// return the master's seat in the created game room
+	return seat(client), nil
 }
 
-func (s *GameService) AddToGame(c *websocket.Conn, room string, done chan bool) (*games.Game, error) {
+// AddToGame seats the connection in the game room. A user can only hold one
+// seat in a room; an empty userID joins as a guest.
+func (s *GameService) AddToGame(ctx context.Context, c *sockets.Client, room string, name string, userID string) (*games.GameClient, error) {
 	// get access to games map
 	log.Print("[AddToGame] - Getting gameService lock")
 	s.mutex.Lock()
@@ -95,40 +200,55 @@ func (s *GameService) AddToGame(c *websocket.Conn, room string, done chan bool)
 	defer log.Print("[AddToGame] - Releasing gameService lock")
 
 	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
 	game, ok := s.games[room]
 	if !ok {
-		var g *games.Game
-		err := fmt.Errorf("game room %s does not exist - failed to join game", room)
+		err := fmt.Errorf("game room %s does not exist - failed to join game: %w", room, ErrGameNotFound)
 		log.Print(err.Error())
-		done <- false
-		return g, err
-	} 
-	
+		return nil, err
+	}
+
 	// get access to game room
 	log.Printf("[AddToGame] - Getting game %s lock", game.Room)
 	game.Mutex.Lock()
 	defer game.Mutex.Unlock()
 	defer log.Printf("[AddToGame] - Releasing game %s lock", game.Room)
 
-	client := s.CreateGameClient(c, room)
+	if err := abandoned(ctx, "join game", room); err != nil {
+		return nil, err
+	}
+
+	if err := canJoinGame(game, c, name, userID); err != nil {
+		err = fmt.Errorf("failed to join game %s: %w", room, err)
+		log.Print(err.Error())
+		return nil, err
+	}
+
+	if name == "" {
+		name = defaultName(game)
+	}
+
+	client := s.CreateGameClient(c, room, name, userID)
 	game.Clients[c] = client
 
 	message := fmt.Sprintf("client %s joined game %s", client.UserID, room)
 	log.Print(message)
 
-	// serverResponse := responses.SocketResponse{
-	// 	Status: responses.Success,
-	// 	Message: message,
-	// 	}
-
-	// game.Broadcast <- serverResponse
-	done <- true
-
-	return game, nil
-	
+	s.broadcast(game, responses.SocketEvent{
+		Event: responses.EventPlayerJoined,
+		Message: message,
+		Content: map[string]interface{}{
+			"room": room,
+			"userID": client.UserID,
+			"name": client.Name,
+			"players": len(game.Clients),
+		},
+	})
+
+	return seat(client), nil
 }
 
-func (s *GameService) RemoveFromGame(c *websocket.Conn, room string, done chan bool) (*games.Game, error) {
+func (s *GameService) RemoveFromGame(ctx context.Context, c *sockets.Client, room string) error {
 	// get access to games map
 	log.Print("[RemoveFromGame] - Getting gameService lock")
 	s.mutex.Lock()
@@ -136,13 +256,12 @@ func (s *GameService) RemoveFromGame(c *websocket.Conn, room string, done chan b
 	defer log.Print("[RemoveFromGame] - Releasing gameService lock")
 
 	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
 	game, ok := s.games[room]
 	if !ok {
-		var g *games.Game
-		done <- false
-		err := fmt.Errorf("game room %s does not exist - failed to leave game", room)
+		err := fmt.Errorf("game room %s does not exist - failed to leave game: %w", room, ErrGameNotFound)
 		log.Print(err.Error())
-		return g, err
+		return err
 	}
 
 	// get access to game room
@@ -151,13 +270,15 @@ func (s *GameService) RemoveFromGame(c *websocket.Conn, room string, done chan b
 	defer game.Mutex.Unlock()
 	defer log.Printf("[RemoveFromGame] - Releasing game %s lock", game.Room)
 
+	if err := abandoned(ctx, "leave game", room); err != nil {
+		return err
+	}
+
 	client, ok := game.Clients[c]
 	if !ok {
-		var g *games.Game
-		done <- false
-		err := fmt.Errorf("game client does not exist in room %s - failed to leave game", room)
+		err := fmt.Errorf("game client does not exist in room %s - failed to leave game: %w", room, ErrNotInGame)
 		log.Print(err.Error())
-		return g, err
+		return err
 	}
 
 	clientID := client.UserID
@@ -169,21 +290,206 @@ func (s *GameService) RemoveFromGame(c *websocket.Conn, room string, done chan b
 
 	log.Print(message)
 
-	// if len(game.Clients) == 0 {
-	// 	// remove game room from game service map if no clients remain
-	// 	defer delete(s.games, room)
-	// 	log.Printf("Deleted game room %s. No players remaining.", room)
-	// }
+	if len(game.Clients) == 0 {
+		// remove game room from game service map if no clients remain
+		s.deleteGame(game)
+		log.Printf("Deleted game room %s. No players remaining.", room)
+		return nil
+	}
+
+	s.broadcast(game, responses.SocketEvent{
+		Event: responses.EventPlayerLeft,
+		Message: message,
+		Content: map[string]interface{}{
+			"room": room,
+			"userID": clientID,
+			"players": len(game.Clients),
+		},
+	})
+
+	if clientID == game.MasterID {
+		s.promoteMaster(game)
+	}
+
+	// the leaving player may have been the last one holding up the phase
+	s.advance(game)
+
+	return nil
+}
+
+func (s *GameService) ChangePlayerName(ctx context.Context, c *sockets.Client, room string, name string) error {
+	// get access to games map
+	log.Print("[ChangePlayerName] - Getting gameService lock")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer log.Print("[ChangePlayerName] - Releasing gameService lock")
+
+	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
+	game, ok := s.games[room]
+	if !ok {
+		err := fmt.Errorf("game room %s does not exist - failed to change name: %w", room, ErrGameNotFound)
+		log.Print(err.Error())
+		return err
+	}
+
+	// get access to game room
+	log.Printf("[ChangePlayerName] - Getting game %s lock", game.Room)
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+	defer log.Printf("[ChangePlayerName] - Releasing game %s lock", game.Room)
+
+	if err := abandoned(ctx, "change name", room); err != nil {
+		return err
+	}
+
+	client, ok := game.Clients[c]
+	if !ok {
+		err := fmt.Errorf("game client does not exist in room %s - failed to change name: %w", room, ErrNotInGame)
+		log.Print(err.Error())
+		return err
+	}
+
+	if nameTaken(game, name, client) {
+		err := fmt.Errorf("name %s is taken in game room %s - failed to change name: %w", name, room, ErrNameTaken)
+		log.Print(err.Error())
+		return err
+	}
+
+	oldName := client.Name
+	client.Name = name
+
+	message := fmt.Sprintf("%s is now called %s", oldName, name)
+	log.Printf("Client %s in game room %s changed name from %s to %s", client.UserID, room, oldName, name)
+
+	s.broadcast(game, responses.SocketEvent{
+		Event: responses.EventPlayerRenamed,
+		Message: message,
+		Content: map[string]interface{}{
+			"room": room,
+			"userID": client.UserID,
+			"name": name,
+			"oldName": oldName,
+		},
+	})
 
-	// serverResponse := responses.SocketResponse{
-	// 	Status: responses.Success,
-	// 	Message: message,
-	// 	}
+	return nil
+}
 
-	// game.Broadcast <- serverResponse
-	done <- true
+// Disconnect marks the client as disconnected in every game room it is in,
+// handing the master role to another player where it held it. The player
+// keeps their seat for the configured ResumeGrace so they can resume from a
+// new connection.
+func (s *GameService) Disconnect(c *sockets.Client) {
+	// get access to games map
+	log.Print("[Disconnect] - Getting gameService lock")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer log.Print("[Disconnect] - Releasing gameService lock")
+
+	for _, game := range s.games {
+		s.disconnectFromGame(game, c)
+	}
+}
+
+func (s *GameService) disconnectFromGame(game *games.Game, c *sockets.Client) {
+	// get access to game room
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+
+	client, ok := game.Clients[c]
+	if !ok {
+		return
+	}
+
+	client.Connected = false
+	client.DisconnectedAt = time.Now()
+	log.Printf("Client %s disconnected from game room %s", client.UserID, game.Room)
+
+	s.broadcast(game, responses.SocketEvent{
+		Event: responses.EventPlayerDisconnected,
+		Message: fmt.Sprintf("%s lost connection", client.Name),
+		Content: map[string]interface{}{
+			"room": game.Room,
+			"userID": client.UserID,
+			"connected": false,
+		},
+	})
+
+	// hold the seat for a while in case the player comes back
+	room, userID, disconnectedAt := game.Room, client.UserID, client.DisconnectedAt
+	time.AfterFunc(s.config.ResumeGrace, func() {
+		s.expireSeat(room, userID, disconnectedAt)
+	})
+
+	if client.UserID == game.MasterID {
+		s.promoteMaster(game)
+	}
+
+	s.advance(game)
+}
 
-	return game, nil
+func (s *GameService) StartGame(ctx context.Context, c *sockets.Client, room string) error {
+	// get access to games map
+	log.Print("[StartGame] - Getting gameService lock")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer log.Print("[StartGame] - Releasing gameService lock")
+
+	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
+	game, ok := s.games[room]
+	if !ok {
+		err := fmt.Errorf("game room %s does not exist - failed to start game: %w", room, ErrGameNotFound)
+		log.Print(err.Error())
+		return err
+	}
+
+	// get access to game room
+	log.Printf("[StartGame] - Getting game %s lock", game.Room)
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+	defer log.Printf("[StartGame] - Releasing game %s lock", game.Room)
+
+	if err := abandoned(ctx, "start game", room); err != nil {
+		return err
+	}
+
+	if err := canStartGame(game, c); err != nil {
+		err = fmt.Errorf("failed to start game %s: %w", room, err)
+		log.Print(err.Error())
+		return err
+	}
+
+	if err := s.setPhase(game, games.PhaseWritingPrompts); err != nil {
+		return err
+	}
+	log.Printf("Game %s started with %d players", room, len(game.Clients))
+
+	return nil
+}
+
+// canStartGame checks that the client may start the game in its current state.
+// The caller must hold the game lock.
+func canStartGame(game *games.Game, c *sockets.Client) error {
+	client, ok := game.Clients[c]
+	if !ok {
+		return ErrNotInGame
+	}
+	if client.UserID != game.MasterID {
+		return ErrNotMaster
+	}
+	if err := checkPhase(game, messages.MessageTypeStartGame); err != nil {
+		return err
+	}
+	// a seat kept for a player who lost their connection can't play yet
+	if connectedCount(game) < games.MinPlayers {
+		return ErrNotEnoughPlayers
+	}
+	if len(game.Clients) > game.MaxPlayers {
+		return ErrTooManyPlayers
+	}
+	return nil
 }
 
 func (s *GameService) ServiceHealth() GameServiceState {
@@ -198,8 +504,11 @@ func (s *GameService) ServiceHealth() GameServiceState {
 
 	for room, game := range s.games {
 		gameStates[room] = games.GameState{
+			ID: game.ID,
 			Clients: len(game.Clients),
+			MaxPlayers: game.MaxPlayers,
 			Status: game.Status,
+			Phase: game.Phase,
 			Room: room,
 		}
 	}
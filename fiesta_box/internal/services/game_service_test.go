@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
+)
+
+// dialTestSocket connects a client over a real websocket and returns it with
+// the peer it talks to. Nothing writes for the client until WritePump runs.
+func dialTestSocket(t *testing.T, config sockets.Config) (*sockets.Client, *websocket.Conn) {
+	t.Helper()
+
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("could not connect: %v", err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	c := sockets.NewClientWithConfig(<-conns, config)
+	t.Cleanup(c.Close)
+	return c, peer
+}
+
+// newTestSocket is a connected client whose peer reads and drops everything
+// the room sends it.
+func newTestSocket(t *testing.T) *sockets.Client {
+	t.Helper()
+
+	c, peer := dialTestSocket(t, sockets.DefaultConfig)
+	go c.WritePump()
+	go func() {
+		for {
+			if _, _, err := peer.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func TestCancelledRequestLeavesGameUntouched(t *testing.T) {
+	game := newTestGame("ROOM", time.Now(), true)
+	game.Phase = games.PhaseLobby
+	game.MaxPlayers = games.MaxPlayers
+
+	s := NewGameService()
+	s.games = map[string]*games.Game{"ROOM": game}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.AddToGame(ctx, &sockets.Client{}, "room", "Ann", ""); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(game.Clients) != 1 {
+		t.Fatalf("expected the cancelled join to leave 1 player, got %d", len(game.Clients))
+	}
+	if len(game.Broadcast) != 0 {
+		t.Fatalf("expected nothing broadcast, got %d responses", len(game.Broadcast))
+	}
+
+	client, err := s.AddToGame(context.Background(), &sockets.Client{}, "room", "Ann", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.Room != "ROOM" || client.Name != "Ann" {
+		t.Fatalf("unexpected seat %+v", client)
+	}
+}
+
+func TestCancelledRequestCreatesNoGame(t *testing.T) {
+	s := NewGameService()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.NewGame(ctx, &sockets.Client{}, "Ann", "")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if want := "gave up trying to create game: context canceled"; err.Error() != want {
+		t.Fatalf("expected %q, got %q", want, err.Error())
+	}
+	if len(s.games) != 0 {
+		t.Fatalf("expected no game to be created, got %d", len(s.games))
+	}
+}
+
+func TestUserHoldsOneSeatPerRoom(t *testing.T) {
+	game := newTestGame("ROOM", time.Now(), true)
+	game.Phase = games.PhaseLobby
+	game.MaxPlayers = games.MaxPlayers
+
+	s := NewGameService()
+	s.games = map[string]*games.Game{"ROOM": game}
+
+	client, err := s.AddToGame(context.Background(), &sockets.Client{}, "room", "Ann", "user-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.UserID != "user-1" {
+		t.Fatalf("expected to be seated as user-1, got %s", client.UserID)
+	}
+
+	if _, err := s.AddToGame(context.Background(), &sockets.Client{}, "room", "Ann again", "user-1"); !errors.Is(err, ErrAlreadyInGame) {
+		t.Fatalf("expected ErrAlreadyInGame, got %v", err)
+	}
+}
+
+func TestResumeSessionKeepsOneSeatPerConnection(t *testing.T) {
+	ann, oldBob := &sockets.Client{}, &sockets.Client{}
+	game := newTestGame("ROOM", time.Now())
+	game.Clients[ann] = &games.GameClient{Room: "ROOM", Client: ann, UserID: "ann", Connected: true, ResumeToken: "ann-token"}
+	game.Clients[oldBob] = &games.GameClient{Room: "ROOM", Client: oldBob, UserID: "bob", ResumeToken: "bob-token"}
+
+	s := NewGameService()
+	s.games = map[string]*games.Game{"ROOM": game}
+
+	if _, err := s.ResumeSession(context.Background(), ann, "room", "bob-token"); !errors.Is(err, ErrAlreadyInGame) {
+		t.Fatalf("expected ErrAlreadyInGame, got %v", err)
+	}
+	if len(game.Clients) != 2 || game.Clients[ann].UserID != "ann" || game.Clients[oldBob].Connected {
+		t.Fatalf("expected both seats untouched, got %+v", game.Clients)
+	}
+}
+
+func TestStartGameChecks(t *testing.T) {
+	ann, bob := &sockets.Client{}, &sockets.Client{}
+	newLobby := func() (*GameService, *games.Game) {
+		game := newTestGame("ROOM", time.Now())
+		game.Phase = games.PhaseLobby
+		game.Status = games.NotStarted
+		game.MaxPlayers = games.MaxPlayers
+		game.Prompts = prompts.NewPool()
+		game.MasterID = "ann"
+		game.Clients[ann] = &games.GameClient{Room: "ROOM", Client: ann, UserID: "ann", Connected: true}
+		game.Clients[bob] = &games.GameClient{Room: "ROOM", Client: bob, UserID: "bob", Connected: true}
+
+		s := NewGameService()
+		s.games = map[string]*games.Game{"ROOM": game}
+		return s, game
+	}
+
+	t.Run("only the master", func(t *testing.T) {
+		s, _ := newLobby()
+		if err := s.StartGame(context.Background(), bob, "ROOM"); !errors.Is(err, ErrNotMaster) {
+			t.Fatalf("expected ErrNotMaster, got %v", err)
+		}
+	})
+
+	t.Run("too few players", func(t *testing.T) {
+		s, game := newLobby()
+		game.Clients[bob].Connected = false
+		if err := s.StartGame(context.Background(), ann, "ROOM"); !errors.Is(err, ErrNotEnoughPlayers) {
+			t.Fatalf("expected ErrNotEnoughPlayers with bob disconnected, got %v", err)
+		}
+	})
+
+	t.Run("too many players", func(t *testing.T) {
+		s, game := newLobby()
+		game.MaxPlayers = 1
+		if err := s.StartGame(context.Background(), ann, "ROOM"); !errors.Is(err, ErrTooManyPlayers) {
+			t.Fatalf("expected ErrTooManyPlayers, got %v", err)
+		}
+	})
+
+	t.Run("starts", func(t *testing.T) {
+		s, game := newLobby()
+		if err := s.StartGame(context.Background(), ann, "ROOM"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if game.Status != games.Started || game.Phase != games.PhaseWritingPrompts {
+			t.Fatalf("expected the game to be started, got %v in the %s phase", game.Status, game.Phase)
+		}
+		if event := <-game.Broadcast; event.Event != responses.EventPhaseChanged {
+			t.Fatalf("expected the room to be told, got %+v", event)
+		}
+		if err := s.StartGame(context.Background(), ann, "ROOM"); !errors.Is(err, ErrWrongPhase) {
+			t.Fatalf("expected a started game not to start again, got %v", err)
+		}
+	})
+}
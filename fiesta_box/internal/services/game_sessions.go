@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/responses"
+	"fiesta_box/internal/sockets"
+)
+
+var ErrInvalidResumeToken = errors.New("resume token is not valid for this game room")
+
+// newResumeToken creates the secret a player presents to take their seat back.
+func newResumeToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("could not generate resume token: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// seat copies the client so handlers can read it without holding the game lock.
+func seat(client *games.GameClient) *games.GameClient {
+	copied := *client
+	return &copied
+}
+
+func (s *GameService) ResumeSession(ctx context.Context, c *sockets.Client, room string, token string) (map[string]interface{}, error) {
+	// get access to games map
+	log.Print("[ResumeSession] - Getting gameService lock")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer log.Print("[ResumeSession] - Releasing gameService lock")
+
+	// check if room exists, fail if it doesn't
+	room = normalizeRoomCode(room)
+	game, ok := s.games[room]
+	if !ok {
+		err := fmt.Errorf("game room %s does not exist - failed to resume session: %w", room, ErrGameNotFound)
+		log.Print(err.Error())
+		return nil, err
+	}
+
+	// get access to game room
+	log.Printf("[ResumeSession] - Getting game %s lock", game.Room)
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+	defer log.Printf("[ResumeSession] - Releasing game %s lock", game.Room)
+
+	if err := abandoned(ctx, "resume session", room); err != nil {
+		return nil, err
+	}
+
+	var client *games.GameClient
+	for _, candidate := range game.Clients {
+		if token != "" && candidate.ResumeToken == token {
+			client = candidate
+			break
+		}
+	}
+	if client == nil {
+		err := fmt.Errorf("failed to resume session in game %s: %w", room, ErrInvalidResumeToken)
+		log.Print(err.Error())
+		return nil, err
+	}
+
+	// taking another seat would leave this connection's own seat behind
+	if seated, ok := game.Clients[c]; ok && seated != client {
+		err := fmt.Errorf("client %s already has a seat in game %s - failed to resume session: %w", seated.UserID, room, ErrAlreadyInGame)
+		log.Print(err.Error())
+		return nil, err
+	}
+
+	// the old connection may not have noticed it is dead yet
+	if client.Client != c {
+		old := client.Client
+		delete(game.Clients, old)
+		old.Close()
+	}
+
+	client.Client = c
+	client.Connected = true
+	client.DisconnectedAt = time.Time{}
+	game.Clients[c] = client
+
+	message := fmt.Sprintf("%s reconnected", client.Name)
+	log.Printf("Client %s resumed session in game room %s", client.UserID, room)
+
+	s.broadcast(game, responses.SocketEvent{
+		Event:   responses.EventPlayerReconnected,
+		Message: message,
+		Content: map[string]interface{}{
+			"room":   room,
+			"userID": client.UserID,
+			"name":   client.Name,
+		},
+	})
+
+	// a game left without a master gets one back
+	if game.MasterID == "" {
+		s.setMaster(game, client.UserID)
+	}
+
+	return gameSnapshot(game, client), nil
+}
+
+// expireSeat removes a player who did not come back within the grace period.
+func (s *GameService) expireSeat(room string, userID string, disconnectedAt time.Time) {
+	// get access to games map
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	game, ok := s.games[normalizeRoomCode(room)]
+	if !ok {
+		return
+	}
+
+	// get access to game room
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+
+	client := findClient(game, userID)
+	if client == nil || client.Connected || !client.DisconnectedAt.Equal(disconnectedAt) {
+		return
+	}
+
+	delete(game.Clients, client.Client)
+
+	message := fmt.Sprintf("Client %s left game room %s", userID, room)
+	log.Printf("Client %s did not reconnect to game room %s in time", userID, room)
+
+	if len(game.Clients) == 0 {
+		s.deleteGame(game)
+		log.Printf("Deleted game room %s. No players remaining.", room)
+		return
+	}
+
+	s.broadcast(game, responses.SocketEvent{
+		Event:   responses.EventPlayerLeft,
+		Message: message,
+		Content: map[string]interface{}{
+			"room":    room,
+			"userID":  userID,
+			"players": len(game.Clients),
+		},
+	})
+
+	// a room whose master left while nobody else was connected is still
+	// waiting for one
+	if game.MasterID == "" || game.MasterID == userID {
+		s.promoteMaster(game)
+	}
+
+	s.advance(game)
+}
+
+// gameSnapshot describes the game as the client should see it, so a
+// reconnecting player can rebuild their screen. The caller must hold the
+// game lock.
+func gameSnapshot(game *games.Game, client *games.GameClient) map[string]interface{} {
+	players := []map[string]interface{}{}
+	for _, player := range game.Clients {
+		score := games.Score{}
+		if s, ok := game.Scores[player.UserID]; ok {
+			score = *s
+		}
+		players = append(players, map[string]interface{}{
+			"userID":    player.UserID,
+			"name":      player.Name,
+			"connected": player.Connected,
+			"written":   game.Prompts.CountBy(player.UserID),
+			"performed": score.Performed,
+			"drank":     score.Drank,
+		})
+	}
+
+	state := map[string]interface{}{
+		"room":             game.Room,
+		"userID":           client.UserID,
+		"name":             client.Name,
+		"resumeToken":      client.ResumeToken,
+		"masterID":         game.MasterID,
+		"phase":            game.Phase,
+		"status":           game.Status,
+		"round":            game.Prompts.Round,
+		"rounds":           game.RoundLimit,
+		"promptsPerPlayer": game.Prompts.PerPlayer,
+		"promptsReady":     game.Prompts.Ready,
+		"players":          players,
+		"results":          game.Results,
+	}
+
+	if id, ok := game.Prompts.Held[client.UserID]; ok {
+		state["promptID"] = id
+		state["prompt"] = game.Prompts.Prompts[id].Text
+	}
+
+	return state
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newSessionService(grace time.Duration) *GameService {
+	config := DefaultGameConfig
+	config.ResumeGrace = grace
+	return NewGameServiceWithConfig(config)
+}
+
+func TestResumeSessionWithinGrace(t *testing.T) {
+	s := newSessionService(time.Hour)
+	ctx := context.Background()
+	old, fresh := newTestSocket(t), newTestSocket(t)
+
+	ann, err := s.NewGame(ctx, old, "Ann", "")
+	if err != nil {
+		t.Fatalf("could not create game: %v", err)
+	}
+	s.Disconnect(old)
+
+	snapshot, err := s.ResumeSession(ctx, fresh, ann.Room, ann.ResumeToken)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snapshot["userID"] != ann.UserID || snapshot["room"] != ann.Room || snapshot["masterID"] != ann.UserID {
+		t.Fatalf("expected Ann's seat back as master, got %v", snapshot)
+	}
+
+	game := s.games[ann.Room]
+	game.Mutex.Lock()
+	defer game.Mutex.Unlock()
+	client, ok := game.Clients[fresh]
+	if !ok || client.UserID != ann.UserID || !client.Connected || !client.DisconnectedAt.IsZero() {
+		t.Fatalf("expected the new connection to hold Ann's seat, got %+v", client)
+	}
+	if _, ok := game.Clients[old]; ok || len(game.Clients) != 1 {
+		t.Fatalf("expected the old connection to be gone, got %d seats", len(game.Clients))
+	}
+}
+
+func TestResumeSessionWithBadToken(t *testing.T) {
+	s := newSessionService(time.Hour)
+	ctx := context.Background()
+	old := newTestSocket(t)
+
+	ann, err := s.NewGame(ctx, old, "Ann", "")
+	if err != nil {
+		t.Fatalf("could not create game: %v", err)
+	}
+	s.Disconnect(old)
+
+	for _, token := range []string{"", "not-the-token"} {
+		if _, err := s.ResumeSession(ctx, newTestSocket(t), ann.Room, token); !errors.Is(err, ErrInvalidResumeToken) {
+			t.Fatalf("expected ErrInvalidResumeToken for %q, got %v", token, err)
+		}
+	}
+}
+
+func TestSeatExpiresAfterGrace(t *testing.T) {
+	s := newSessionService(50 * time.Millisecond)
+	ctx := context.Background()
+	annConn, catConn := newTestSocket(t), newTestSocket(t)
+
+	ann, err := s.NewGame(ctx, annConn, "Ann", "")
+	if err != nil {
+		t.Fatalf("could not create game: %v", err)
+	}
+	// nobody is left to take over when Ann drops, so Cat joins a room
+	// without a master
+	s.Disconnect(annConn)
+	cat, err := s.AddToGame(ctx, catConn, ann.Room, "Cat", "")
+	if err != nil {
+		t.Fatalf("could not join: %v", err)
+	}
+
+	game := s.games[ann.Room]
+	state := func() (int, string) {
+		game.Mutex.Lock()
+		defer game.Mutex.Unlock()
+		return len(game.Clients), game.MasterID
+	}
+	if seats, master := state(); seats != 2 || master != "" {
+		t.Fatalf("expected Ann's seat to be held with no master, got %d seats and master %q", seats, master)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		seats, master := state()
+		if seats == 1 && master == cat.UserID {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected Ann's seat to expire and Cat to be promoted, got %d seats and master %q", seats, master)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := s.ResumeSession(ctx, newTestSocket(t), ann.Room, ann.ResumeToken); !errors.Is(err, ErrInvalidResumeToken) {
+		t.Fatalf("expected an expired seat not to be resumed, got %v", err)
+	}
+}
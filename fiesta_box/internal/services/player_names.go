@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"fiesta_box/internal/models/games"
+)
+
+// MaxNameLength is the longest display name a player can pick, in characters.
+const MaxNameLength = 20
+
+// disallowedNameChars are kept out of names so clients can render them as-is.
+const disallowedNameChars = "<>&\"'`/\\{}"
+
+var (
+	ErrInvalidName = errors.New("invalid player name")
+	ErrNameTaken   = errors.New("player name is already taken in this game room")
+)
+
+// NormalizeName trims the name, collapses runs of whitespace and checks that
+// what is left is a usable display name.
+func NormalizeName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+
+	length := utf8.RuneCountInString(name)
+	if length == 0 {
+		return "", fmt.Errorf("%w: name cannot be empty", ErrInvalidName)
+	}
+	if length > MaxNameLength {
+		return "", fmt.Errorf("%w: name cannot be longer than %d characters", ErrInvalidName, MaxNameLength)
+	}
+
+	for _, r := range name {
+		if unicode.IsControl(r) || !unicode.IsPrint(r) || strings.ContainsRune(disallowedNameChars, r) {
+			return "", fmt.Errorf("%w: name cannot contain %q", ErrInvalidName, r)
+		}
+	}
+
+	return name, nil
+}
+
+// nameTaken reports whether another client in the game room already uses the
+// name. Names are compared case-insensitively. The caller must hold the game lock.
+func nameTaken(game *games.Game, name string, except *games.GameClient) bool {
+	for _, client := range game.Clients {
+		if client != except && strings.EqualFold(client.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultName picks the first free "Player N" name in the game room.
+// The caller must hold the game lock.
+func defaultName(game *games.Game) string {
+	for i := len(game.Clients) + 1; ; i++ {
+		name := fmt.Sprintf("Player %d", i)
+		if !nameTaken(game, name, nil) {
+			return name
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/sockets"
+)
+
+func TestNormalizeName(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		expected string
+		valid    bool
+	}{
+		{"Ann", "Ann", true},
+		{"  Ann  ", "Ann", true},
+		{"Ann \t  Marie", "Ann Marie", true},
+		{"Zoë 🎉", "Zoë 🎉", true},
+		{strings.Repeat("a", MaxNameLength), strings.Repeat("a", MaxNameLength), true},
+		{strings.Repeat("é", MaxNameLength), strings.Repeat("é", MaxNameLength), true},
+		{strings.Repeat("a", MaxNameLength+1), "", false},
+		{"", "", false},
+		{"   ", "", false},
+		{"<script>", "", false},
+		{"Ann & Bob", "", false},
+		{"Ann/Bob", "", false},
+		{"Ann\u0007", "", false},
+	} {
+		name, err := NormalizeName(test.name)
+		if test.valid && (err != nil || name != test.expected) {
+			t.Errorf("%q: expected %q, got %q %v", test.name, test.expected, name, err)
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidName) {
+			t.Errorf("%q: expected ErrInvalidName, got %q %v", test.name, name, err)
+		}
+	}
+}
+
+func TestNameTakenIgnoresCase(t *testing.T) {
+	game := newTestGame("ROOM", time.Now())
+	ann := &games.GameClient{Name: "Ann"}
+	game.Clients[&sockets.Client{}] = ann
+
+	for name, taken := range map[string]bool{"Ann": true, "ANN": true, "ann": true, "Anna": false} {
+		if got := nameTaken(game, name, nil); got != taken {
+			t.Errorf("%q: expected taken=%v, got %v", name, taken, got)
+		}
+	}
+	if nameTaken(game, "ANN", ann) {
+		t.Error("expected a player's own name not to count against them")
+	}
+}
+
+func TestDefaultNameSkipsTakenNames(t *testing.T) {
+	game := newTestGame("ROOM", time.Now())
+	if name := defaultName(game); name != "Player 1" {
+		t.Fatalf("expected Player 1 in an empty room, got %q", name)
+	}
+
+	game.Clients[&sockets.Client{}] = &games.GameClient{Name: "Ann"}
+	game.Clients[&sockets.Client{}] = &games.GameClient{Name: "player 3"}
+	if name := defaultName(game); name != "Player 4" {
+		t.Fatalf("expected Player 4 with Player 3 taken, got %q", name)
+	}
+}
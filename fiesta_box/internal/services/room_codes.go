@@ -0,0 +1,61 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+)
+
+const (
+	// roomCodeAlphabet leaves out characters that are easy to mix up on a
+	// phone screen: 0 and O, 1 and I.
+	roomCodeAlphabet  = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	minRoomCodeLength = 4
+	maxRoomCodeLength = 6
+	// roomCodeAttempts is how many codes of one length are tried before
+	// moving on to longer codes.
+	roomCodeAttempts = 10
+)
+
+var ErrNoRoomCodes = errors.New("could not find a free room code")
+
+// roomCodeRandom is where room codes get their randomness from.
+var roomCodeRandom io.Reader = rand.Reader
+
+// newRoomCode picks a random room code that no game is using. Short codes
+// are preferred; longer ones are only used when short ones keep colliding.
+// The caller must hold the gameService lock.
+func (s *GameService) newRoomCode() (string, error) {
+	for length := minRoomCodeLength; length <= maxRoomCodeLength; length++ {
+		for attempt := 0; attempt < roomCodeAttempts; attempt++ {
+			code, err := randomRoomCode(length)
+			if err != nil {
+				return "", err
+			}
+			if _, ok := s.games[code]; !ok {
+				return code, nil
+			}
+		}
+	}
+	return "", ErrNoRoomCodes
+}
+
+func randomRoomCode(length int) (string, error) {
+	max := big.NewInt(int64(len(roomCodeAlphabet)))
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(roomCodeRandom, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = roomCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// normalizeRoomCode makes room lookups ignore case and stray whitespace.
+func normalizeRoomCode(room string) string {
+	return strings.ToUpper(strings.TrimSpace(room))
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/sockets"
+)
+
+// zeroReader always draws the first letter of the alphabet.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func useRoomCodeRandom(t *testing.T, r io.Reader) {
+	previous := roomCodeRandom
+	roomCodeRandom = r
+	t.Cleanup(func() { roomCodeRandom = previous })
+}
+
+func TestRoomCodesAvoidLookalikes(t *testing.T) {
+	for _, c := range "0O1I" {
+		if strings.ContainsRune(roomCodeAlphabet, c) {
+			t.Fatalf("expected %q to be left out of the alphabet", c)
+		}
+	}
+
+	s := NewGameService()
+	for i := 0; i < 1000; i++ {
+		code, err := s.newRoomCode()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(code) != minRoomCodeLength {
+			t.Fatalf("expected a %d character code, got %q", minRoomCodeLength, code)
+		}
+		if strings.Trim(code, roomCodeAlphabet) != "" {
+			t.Fatalf("expected only alphabet characters, got %q", code)
+		}
+	}
+}
+
+func TestRoomCodesGrowWhenShortOnesCollide(t *testing.T) {
+	useRoomCodeRandom(t, zeroReader{})
+
+	s := NewGameService()
+	s.games = map[string]*games.Game{"AAAA": newTestGame("AAAA", time.Now())}
+
+	code, err := s.newRoomCode()
+	if err != nil || code != "AAAAA" {
+		t.Fatalf("expected a longer code after the short one collided, got %q %v", code, err)
+	}
+
+	s.games["AAAAA"] = newTestGame("AAAAA", time.Now())
+	s.games["AAAAAA"] = newTestGame("AAAAAA", time.Now())
+	if code, err := s.newRoomCode(); !errors.Is(err, ErrNoRoomCodes) {
+		t.Fatalf("expected ErrNoRoomCodes once every length collides, got %q %v", code, err)
+	}
+}
+
+func TestRoomCodesIgnoreCase(t *testing.T) {
+	c := &sockets.Client{}
+	game := newTestGame("ABCD", time.Now())
+	game.Phase = games.PhaseLobby
+	game.MaxPlayers = games.MaxPlayers
+
+	s := NewGameService()
+	s.games = map[string]*games.Game{"ABCD": game}
+
+	client, err := s.AddToGame(context.Background(), c, " abcd ", "Ann", "")
+	if err != nil || client.Room != "ABCD" {
+		t.Fatalf("expected to join ABCD, got %+v %v", client, err)
+	}
+
+	if _, err := s.ResumeSession(context.Background(), c, "aBcD", client.ResumeToken); err != nil {
+		t.Fatalf("expected to resume in ABCD, got %v", err)
+	}
+}
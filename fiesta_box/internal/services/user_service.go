@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+
+	"fiesta_box/internal/database"
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/users"
+)
+
+var (
+	ErrInvalidUserToken = errors.New("user token is not valid")
+	// ErrUserNotFound is database.ErrUserNotFound, for callers that only
+	// deal with the service.
+	ErrUserNotFound = database.ErrUserNotFound
+)
+
+// UserService creates the accounts players keep across games and checks the
+// tokens they claim them with.
+type UserService struct {
+	users database.UserRepository
+}
+
+// NewUserService builds the service on the user store. Without a store every
+// call reports database.ErrUnavailable, so games carry on with guests.
+func NewUserService(users database.UserRepository) *UserService {
+	return &UserService{users: users}
+}
+
+// CreateUser saves a new user and returns it with the token that proves who
+// they are. Only a hash of the token is kept, so it can't be shown again.
+func (s *UserService) CreateUser(ctx context.Context, displayName string, avatarURL string) (*users.User, string, error) {
+	name, err := NormalizeName(displayName)
+	if err != nil {
+		return nil, "", err
+	}
+	avatar, err := users.NormalizeAvatarURL(avatarURL)
+	if err != nil {
+		return nil, "", err
+	}
+	if s.users == nil {
+		return nil, "", fmt.Errorf("user can't be created: %w", database.ErrUnavailable)
+	}
+
+	token, err := newUserToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	user := &users.User{DisplayName: name, AvatarURL: avatar, TokenHash: users.HashToken(token)}
+	if err := s.users.Create(ctx, user); err != nil {
+		log.Printf("Could not create user: %v", err)
+		return nil, "", fmt.Errorf("user can't be created: %w", database.ErrUnavailable)
+	}
+	log.Printf("Created user %s", user.ID)
+	return user, token, nil
+}
+
+// GetUser looks the user up. Lookups that fail for any reason other than the
+// user not existing are reported as the store being unavailable.
+func (s *UserService) GetUser(ctx context.Context, id string) (*users.User, error) {
+	if s.users == nil {
+		return nil, fmt.Errorf("user %s can't be loaded: %w", id, database.ErrUnavailable)
+	}
+
+	user, err := s.users.Get(ctx, id)
+	switch {
+	case errors.Is(err, database.ErrUserNotFound):
+		return nil, fmt.Errorf("user %s does not exist: %w", id, err)
+	case err != nil:
+		log.Printf("Could not load user %s: %v", id, err)
+		return nil, fmt.Errorf("user %s can't be loaded: %w", id, database.ErrUnavailable)
+	}
+	return user, nil
+}
+
+// Authenticate returns the user when token is theirs.
+func (s *UserService) Authenticate(ctx context.Context, id string, token string) (*users.User, error) {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.HasToken(token) {
+		return nil, fmt.Errorf("could not sign in as user %s: %w", id, ErrInvalidUserToken)
+	}
+	return user, nil
+}
+
+// History is a user's finished games, newest first, and their totals.
+func (s *UserService) History(ctx context.Context, id string, limit int, offset int) (users.Totals, []users.GameRecord, error) {
+	if _, err := s.GetUser(ctx, id); err != nil {
+		return users.Totals{}, nil, err
+	}
+
+	totals, err := s.users.Totals(ctx, id)
+	if err != nil {
+		log.Printf("Could not add up the games of user %s: %v", id, err)
+		return users.Totals{}, nil, fmt.Errorf("history of user %s can't be loaded: %w", id, database.ErrUnavailable)
+	}
+	history, err := s.users.History(ctx, id, limit, offset)
+	if err != nil {
+		log.Printf("Could not load the history of user %s: %v", id, err)
+		return users.Totals{}, nil, fmt.Errorf("history of user %s can't be loaded: %w", id, database.ErrUnavailable)
+	}
+	return totals, history, nil
+}
+
+// RecordResults adds a finished game to the history of every player who
+// played it with their account. Guests have no history to add to.
+func (s *UserService) RecordResults(ctx context.Context, results *games.Results) error {
+	if s.users == nil {
+		return fmt.Errorf("results of game %s can't be recorded: %w", results.GameID, database.ErrUnavailable)
+	}
+
+	var errs []error
+	for i, player := range results.Players {
+		if !player.Registered {
+			continue
+		}
+		record := users.GameRecord{
+			GameID:      results.GameID,
+			Room:        results.Room,
+			Name:        player.Name,
+			Performed:   player.Performed,
+			Drank:       player.Drank,
+			Rank:        i + 1,
+			Players:     len(results.Players),
+			Rounds:      results.Rounds,
+			MVP:         results.MVP != nil && results.MVP.UserID == player.UserID,
+			CompletedAt: results.CompletedAt,
+		}
+		if err := s.users.RecordGame(ctx, player.UserID, record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func newUserToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate user token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
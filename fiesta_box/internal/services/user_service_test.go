@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"fiesta_box/internal/database"
+	"fiesta_box/internal/models/games"
+	"fiesta_box/internal/models/prompts"
+	"fiesta_box/internal/sockets"
+)
+
+func TestFinishedGamesFollowTheirPlayers(t *testing.T) {
+	ctx := context.Background()
+	accounts := NewUserService(database.New(database.Config{Driver: database.DriverMemory}).Users())
+	ana, _, err := accounts.CreateUser(ctx, "Ana", "")
+	if err != nil {
+		t.Fatalf("could not create user: %v", err)
+	}
+
+	game := newTestGame("ROOM", time.Now())
+	game.ID = "game-1"
+	game.Phase = games.PhasePlaying
+	game.Prompts = prompts.NewPool()
+	game.Scores = map[string]*games.Score{ana.ID: {Performed: 2}}
+	game.Clients[&sockets.Client{}] = &games.GameClient{Room: "ROOM", UserID: ana.ID, Registered: true, Name: "Ana", Connected: true}
+	game.Clients[&sockets.Client{}] = &games.GameClient{Room: "ROOM", UserID: "guest", Name: "Guest", Connected: true}
+
+	s := NewGameService()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	s.RecordResultsWith(func(ctx context.Context, results *games.Results) error {
+		defer wg.Done()
+		return accounts.RecordResults(ctx, results)
+	})
+	s.finish(game)
+	wg.Wait()
+
+	totals, history, err := accounts.History(ctx, ana.ID, 0, 0)
+	if err != nil {
+		t.Fatalf("could not get history: %v", err)
+	}
+	if len(history) != 1 || history[0].GameID != "game-1" || history[0].Rank != 1 || !history[0].MVP || history[0].Players != 2 {
+		t.Fatalf("expected Ana's win to be recorded, got %+v", history)
+	}
+	if totals.Games != 1 || totals.Performed != 2 || totals.MVPs != 1 {
+		t.Fatalf("unexpected totals %+v", totals)
+	}
+}
@@ -0,0 +1,175 @@
+package sockets
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Config tunes how a client talks to its connection.
+type Config struct {
+	// SendBuffer is how many messages a client can fall behind before it is dropped.
+	SendBuffer int
+	// WriteWait is how long a single write to the peer may take.
+	WriteWait time.Duration
+	// PongWait is how long the peer has to answer a ping before the connection is considered dead.
+	PongWait time.Duration
+	// PingPeriod is how often pings are sent. Must be less than PongWait.
+	PingPeriod time.Duration
+	// MaxMessageSize is the largest message accepted from the peer, in bytes.
+	MaxMessageSize int64
+}
+
+var DefaultConfig = Config{
+	SendBuffer:     32,
+	WriteWait:      10 * time.Second,
+	PongWait:       60 * time.Second,
+	PingPeriod:     30 * time.Second,
+	MaxMessageSize: 4096,
+}
+
+var ErrClosed = errors.New("client connection is closed")
+
+// Client wraps a websocket connection so that only one goroutine ever writes
+// to it. Handler replies, room broadcasts and pings are all queued with Send
+// and written by WritePump; reads happen on the caller's goroutine through
+// ReadMessage.
+type Client struct {
+	conn      *websocket.Conn
+	config    Config
+	send      chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func NewClient(conn *websocket.Conn) *Client {
+	return NewClientWithConfig(conn, DefaultConfig)
+}
+
+func NewClientWithConfig(conn *websocket.Conn, config Config) *Client {
+	c := &Client{
+		conn:   conn,
+		config: config,
+		send:   make(chan []byte, config.SendBuffer),
+		closed: make(chan struct{}),
+	}
+
+	conn.SetReadLimit(config.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(config.PongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(config.PongWait))
+	})
+
+	return c
+}
+
+// ReadMessage blocks until the next data message arrives from the peer.
+// It must only be called from a single goroutine.
+func (c *Client) ReadMessage() ([]byte, error) {
+	_, message, err := c.conn.ReadMessage()
+	if err == nil {
+		// any message from the peer proves it is still alive
+		c.conn.SetReadDeadline(time.Now().Add(c.config.PongWait))
+	}
+	return message, err
+}
+
+// Send queues a payload for the writer without blocking. A client whose
+// queue is full is too slow to keep up and gets closed. It reports whether
+// the payload was queued.
+func (c *Client) Send(payload []byte) bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- payload:
+		return true
+	case <-c.closed:
+		return false
+	default:
+		log.Print("[Send] - Client send queue is full, closing connection")
+		c.Close()
+		return false
+	}
+}
+
+// SendJSON marshals v and queues it with Send.
+func (c *Client) SendJSON(v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if !c.Send(payload) {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Close stops the writer, which in turn closes the underlying connection.
+// It is safe to call more than once and from any goroutine.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+}
+
+// Closed is closed once the client has been closed.
+func (c *Client) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// WritePump is the only goroutine allowed to write to the connection. It
+// writes queued payloads and periodic pings until the client is closed or a
+// write fails, then flushes the queue and closes the connection.
+func (c *Client) WritePump() {
+	ticker := time.NewTicker(c.config.PingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.Close()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case payload := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				log.Printf("[WritePump] - Could not write to client: %v", err)
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				log.Printf("[WritePump] - Ping failed: %v", err)
+				return
+			}
+		case <-c.closed:
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+			c.flush()
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+	}
+}
+
+// flush writes whatever was queued before the client was closed, such as a
+// final reply explaining why. Nothing more can be queued once it is closed.
+func (c *Client) flush() {
+	for {
+		select {
+		case payload := <-c.send:
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
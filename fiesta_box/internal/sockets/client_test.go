@@ -0,0 +1,150 @@
+package sockets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dial connects a client over a real websocket and returns it with the peer
+// it talks to.
+func dial(t *testing.T, config Config) (*Client, *websocket.Conn) {
+	t.Helper()
+
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("could not connect: %v", err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	c := NewClientWithConfig(<-conns, config)
+	t.Cleanup(c.Close)
+	return c, peer
+}
+
+func TestSendClosesClientThatFallsBehind(t *testing.T) {
+	config := DefaultConfig
+	config.SendBuffer = 2
+	c, _ := dial(t, config)
+
+	// nothing drains the queue without WritePump
+	done := make(chan bool)
+	go func() {
+		queued := true
+		for i := 0; i <= config.SendBuffer; i++ {
+			queued = c.Send([]byte("{}"))
+		}
+		done <- queued
+	}()
+
+	select {
+	case queued := <-done:
+		if queued {
+			t.Fatal("expected the message past the buffer not to be queued")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Send blocked on a full queue")
+	}
+
+	select {
+	case <-c.Closed():
+	default:
+		t.Fatal("expected the client to be closed")
+	}
+}
+
+func TestSendAfterClose(t *testing.T) {
+	c, _ := dial(t, DefaultConfig)
+	c.Close()
+	c.Close()
+
+	if c.Send([]byte("{}")) {
+		t.Fatal("expected nothing to be queued once closed")
+	}
+	if err := c.SendJSON(map[string]string{"a": "b"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestWritePumpIsTheOnlyWriter(t *testing.T) {
+	config := DefaultConfig
+	config.SendBuffer = 256
+	config.PingPeriod = 5 * time.Millisecond
+	c, peer := dial(t, config)
+	go c.WritePump()
+
+	pings := make(chan struct{}, 1000)
+	peer.SetPingHandler(func(string) error {
+		pings <- struct{}{}
+		return nil
+	})
+
+	// replies and broadcasts are queued from many goroutines at once
+	const senders, each = 8, 20
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < each; j++ {
+				if err := c.SendJSON(map[string]string{"id": fmt.Sprintf("%d-%d", i, j)}); err != nil {
+					t.Errorf("could not send: %v", err)
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}(i)
+	}
+
+	seen := map[string]bool{}
+	peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(seen) < senders*each {
+		_, message, err := peer.ReadMessage()
+		if err != nil {
+			t.Fatalf("read failed after %d messages: %v", len(seen), err)
+		}
+		var m map[string]string
+		if err := json.Unmarshal(message, &m); err != nil {
+			t.Fatalf("garbled frame %q: %v", message, err)
+		}
+		seen[m["id"]] = true
+	}
+	wg.Wait()
+
+	if len(pings) == 0 {
+		t.Fatal("expected pings between the messages")
+	}
+}
+
+func TestCloseFlushesQueuedMessages(t *testing.T) {
+	c, peer := dial(t, DefaultConfig)
+	c.SendJSON(map[string]string{"message": "goodbye"})
+	c.Close()
+	go c.WritePump()
+
+	peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, message, err := peer.ReadMessage()
+	if err != nil || !strings.Contains(string(message), "goodbye") {
+		t.Fatalf("expected the queued reply before the close, got %q %v", message, err)
+	}
+	if _, _, err := peer.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("expected a normal close, got %v", err)
+	}
+}